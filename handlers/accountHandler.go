@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	database "github.com/JosueAD95/Server-course/internal/database"
+)
+
+const (
+	accountDeletionBatchSize       = 100
+	securityEventDeletionRequested = "account_deletion_requested"
+	securityEventDeletionCancelled = "account_deletion_cancelled"
+)
+
+// DeleteAccount schedules the caller's account for deletion once the grace
+// period has passed. Every session and personal access token is revoked
+// straight away; signing in again before the deadline cancels the deletion.
+func (cfg *ApiConfig) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	userId := requestPrincipal(r).UserID
+
+	type parameters struct {
+		Password string `json:"password"`
+	}
+	type response struct {
+		DeleteAfter time.Time `json:"delete_after"`
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		log.Printf("Error decoding JSON: %s", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	user, err := cfg.Db.GetUserById(r.Context(), userId)
+	if err != nil {
+		log.Printf("Error retriaving user '%s': %s", userId.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if !cfg.confirmPassword(w, r, user, params.Password, "Password is incorrect") {
+		return
+	}
+
+	deleteAfter := time.Now().Add(cfg.AccountDeletionGrace)
+
+	tx, err := cfg.DbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Error starting transaction: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.Db.WithTx(tx)
+
+	err = qtx.ScheduleUserDeletion(r.Context(), database.ScheduleUserDeletionParams{
+		ID:          userId,
+		DeleteAfter: deleteAfter,
+	})
+	if err == nil {
+		_, err = qtx.RevokeAllUserSessions(r.Context(), userId)
+	}
+	if err == nil {
+		err = qtx.RevokeAllPersonalAccessTokens(r.Context(), userId)
+	}
+	if err == nil {
+		err = qtx.BumpUserTokenVersion(r.Context(), userId)
+	}
+	if err == nil {
+		err = qtx.CreateSecurityEvent(r.Context(), database.CreateSecurityEventParams{
+			UserID:    userId,
+			EventType: securityEventDeletionRequested,
+			Details:   "account deletion requested from " + clientIP(r),
+		})
+	}
+	if err != nil {
+		log.Printf("Error scheduling deletion of user '%s': %s", userId.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing deletion of user '%s': %s", userId.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, response{DeleteAfter: deleteAfter})
+}
+
+func (cfg *ApiConfig) cancelAccountDeletion(r *http.Request, userId uuid.UUID) error {
+	cancelled, err := cfg.Db.CancelUserDeletion(r.Context(), userId)
+	if err != nil || cancelled == 0 {
+		return err
+	}
+	return cfg.Db.CreateSecurityEvent(r.Context(), database.CreateSecurityEventParams{
+		UserID:    userId,
+		EventType: securityEventDeletionCancelled,
+		Details:   "account deletion cancelled by signing in from " + clientIP(r),
+	})
+}
+
+// DeleteDueAccounts removes the accounts whose grace period is over, with
+// everything the database cascades to. Their uploads and data exports are
+// stored outside it and are deleted here too. It returns how many accounts
+// went.
+func (cfg *ApiConfig) DeleteDueAccounts(ctx context.Context) (int, error) {
+	deleted := 0
+	for {
+		userIds, err := cfg.Db.ListUsersDueForDeletion(ctx, accountDeletionBatchSize)
+		if err != nil {
+			return deleted, err
+		}
+		for _, userId := range userIds {
+			if err := cfg.deleteAccount(ctx, userId); err != nil {
+				return deleted, err
+			}
+			deleted++
+		}
+		if len(userIds) < accountDeletionBatchSize {
+			return deleted, nil
+		}
+	}
+}
+
+func (cfg *ApiConfig) deleteAccount(ctx context.Context, userId uuid.UUID) error {
+	mediaKeys, err := cfg.Db.ListUserMediaKeys(ctx, userId)
+	if err != nil {
+		return err
+	}
+	exportKeys, err := cfg.Db.ListUserDataExportKeys(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	// A sign-in since the listing may have cancelled the deletion, in
+	// which case nothing is removed.
+	removed, err := cfg.Db.DeleteUser(ctx, userId)
+	if err != nil || removed == 0 {
+		return err
+	}
+
+	// The rows are gone, so a file that fails to go now would never be
+	// retried. Log it rather than stop the other deletions.
+	cfg.deleteBlobs(ctx, mediaKeys)
+	for _, key := range exportKeys {
+		if err := cfg.Exports.Delete(ctx, key.String); err != nil {
+			log.Printf("Error deleting export '%s' of deleted user '%s': %s", key.String, userId.String(), err)
+		}
+	}
+	return nil
+}
@@ -1,19 +1,41 @@
 package handler
 
 import (
+	"database/sql"
 	"fmt"
 	"net/http"
 	"sync/atomic"
+	"time"
 
+	"github.com/JosueAD95/Server-course/internal/auth"
 	db "github.com/JosueAD95/Server-course/internal/database"
+	"github.com/JosueAD95/Server-course/internal/export"
+	"github.com/JosueAD95/Server-course/internal/mailer"
+	"github.com/JosueAD95/Server-course/internal/media"
+	"github.com/JosueAD95/Server-course/internal/stream"
 )
 
 type ApiConfig struct {
-	fileserverHits atomic.Int32
-	Db             *db.Queries
-	Environment    string
-	JWTSecret      string
-	PolkaAPIKey    string
+	fileserverHits  atomic.Int32
+	Db              *db.Queries
+	DbConn          *sql.DB
+	Environment     string
+	JWTKeys         *auth.KeyRing
+	PolkaAPIKey     string
+	RefreshTokenTTL time.Duration
+	PasswordPolicy  auth.PasswordPolicy
+	LoginThrottle   auth.LoginThrottle
+	IPLoginThrottle auth.LoginThrottle
+	Blobs           media.BlobStore
+	Stream          stream.Broker
+	Mailer          mailer.Mailer
+	MailQueue       *mailer.Queue
+	BaseURL         string
+	Exports         export.Store
+	ExportLinks     *export.Signer
+
+	RequireVerifiedEmail bool
+	AccountDeletionGrace time.Duration
 }
 
 func (cfg *ApiConfig) MiddlewareMetricsInc(next http.Handler) http.Handler {
@@ -28,11 +50,17 @@ func (cfg *ApiConfig) Reset(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusForbidden)
 		return
 	}
-	err := cfg.Db.DeleteAllUsers(r.Context())
+	mediaKeys, err := cfg.Db.ListAllMediaKeys(r.Context())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	err = cfg.Db.DeleteAllUsers(r.Context())
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	cfg.deleteBlobs(r.Context(), mediaKeys)
 	cfg.fileserverHits.Store(0)
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Hits reset to 0"))
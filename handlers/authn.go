@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/JosueAD95/Server-course/internal/auth"
+)
+
+var (
+	errNoCredentials = errors.New("no bearer token in request")
+	errMissingScope  = errors.New("token is missing the required scope")
+)
+
+// RequireAuth only lets requests with a valid access JWT or personal access
+// token granting scope through to next, which finds the caller with
+// requestPrincipal.
+func (cfg *ApiConfig) RequireAuth(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, err := cfg.authenticate(r, scope)
+		if err != nil {
+			respondWithAuthChallenge(w, scope, err)
+			return
+		}
+		next(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	}
+}
+
+// OptionalAuth serves anonymous requests as well. A token that is present
+// but invalid is still rejected so clients know to refresh it; one that
+// merely lacks scope is treated as anonymous.
+func (cfg *ApiConfig) OptionalAuth(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, err := cfg.authenticate(r, scope)
+		switch {
+		case err == nil:
+			r = r.WithContext(auth.WithPrincipal(r.Context(), principal))
+		case errors.Is(err, errNoCredentials), errors.Is(err, errMissingScope):
+		default:
+			respondWithAuthChallenge(w, scope, err)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// RequirePermission only lets signed-in users whose role grants perm through.
+func (cfg *ApiConfig) RequirePermission(perm auth.Permission, next http.HandlerFunc) http.HandlerFunc {
+	return cfg.RequireAuth(auth.ScopeInteractive, func(w http.ResponseWriter, r *http.Request) {
+		principal := requestPrincipal(r)
+		if !principal.Can(perm) {
+			log.Printf("Forbidden: user '%s' with role '%s' lacks permission '%s'", principal.UserID, principal.Role, perm)
+			respondWithError(w, http.StatusForbidden, "You don't have permission to do this")
+			return
+		}
+		next(w, r)
+	})
+}
+
+// requestPrincipal returns the caller stored by RequireAuth. On routes
+// behind OptionalAuth, check auth.PrincipalFromContext instead.
+func requestPrincipal(r *http.Request) auth.Principal {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+	return principal
+}
+
+func (cfg *ApiConfig) authenticate(r *http.Request, scope string) (auth.Principal, error) {
+	principal := auth.Principal{}
+	if r.Header.Get("Authorization") == "" {
+		return principal, errNoCredentials
+	}
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return principal, err
+	}
+
+	if auth.IsPersonalAccessToken(token) {
+		pat, err := cfg.Db.UsePersonalAccessToken(r.Context(), auth.HashToken(token))
+		if errors.Is(err, sql.ErrNoRows) {
+			return principal, errors.New("unknown, expired or revoked personal access token")
+		}
+		if err != nil {
+			return principal, err
+		}
+		principal.UserID = pat.UserID
+		principal.IsChirpyRed = pat.IsChirpyRed
+		principal.EmailVerified = pat.EmailVerified
+		principal.Scopes = pat.Scopes
+		principal.Role = auth.Role(pat.Role)
+	} else {
+		// The user row is read anyway for the token version check, so
+		// Chirpy Red status, email verification and the role come along
+		// with it.
+		userId, sessionId, err := auth.ValidateJWT(token, cfg.JWTKeys, func(userId uuid.UUID) (int32, error) {
+			state, err := cfg.Db.GetUserAuthState(r.Context(), userId)
+			principal.IsChirpyRed = state.IsChirpyRed
+			principal.EmailVerified = state.EmailVerified
+			principal.Role = auth.Role(state.Role)
+			return state.TokenVersion, err
+		})
+		if err != nil {
+			return principal, err
+		}
+		principal.UserID = userId
+		principal.SessionID = sessionId
+	}
+
+	if !principal.HasScope(scope) {
+		if scope == auth.ScopeInteractive {
+			return principal, fmt.Errorf("%w: personal access tokens can't be used here", errMissingScope)
+		}
+		return principal, fmt.Errorf("%w %q", errMissingScope, scope)
+	}
+	return principal, nil
+}
+
+// respondWithAuthChallenge answers with the RFC 6750 challenge matching
+// err: no error code when credentials are missing, invalid_token when they
+// don't check out and insufficient_scope when the token can't be used here.
+func respondWithAuthChallenge(w http.ResponseWriter, scope string, err error) {
+	switch {
+	case errors.Is(err, errNoCredentials):
+		w.Header().Set("WWW-Authenticate", `Bearer realm="chirpy"`)
+		respondWithError(w, http.StatusUnauthorized, "Authentication required")
+	case errors.Is(err, errMissingScope):
+		log.Printf("Forbidden: %s", err)
+		challenge := `Bearer realm="chirpy", error="insufficient_scope"`
+		if scope != auth.ScopeInteractive {
+			challenge += fmt.Sprintf(`, scope=%q`, scope)
+		}
+		w.Header().Set("WWW-Authenticate", challenge)
+		respondWithError(w, http.StatusForbidden, "Token does not grant access to this resource")
+	default:
+		log.Printf("Couldn't authenticate request: %s", err)
+		w.Header().Set("WWW-Authenticate", `Bearer realm="chirpy", error="invalid_token"`)
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired token")
+	}
+}
@@ -1,35 +1,97 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
-	"sort"
 
 	"github.com/google/uuid"
 
 	"github.com/JosueAD95/Server-course/internal/auth"
 	database "github.com/JosueAD95/Server-course/internal/database"
+	"github.com/JosueAD95/Server-course/internal/entities"
+	"github.com/JosueAD95/Server-course/internal/moderation"
+	"github.com/JosueAD95/Server-course/internal/stream"
 	model "github.com/JosueAD95/Server-course/models"
-	util "github.com/JosueAD95/Server-course/utils"
 )
 
-func (cfg ApiConfig) GetAllChirps(w http.ResponseWriter, r *http.Request) {
+const maxChirpLength = 140
+
+var (
+	errChirpTooLong  = errors.New("Chirp is too long")
+	errChirpRejected = errors.New("Chirp contains banned words")
+)
+
+// moderateChirpBody enforces the length limit and runs body through the
+// banned word list. errChirpTooLong and errChirpRejected are meant for the
+// client, any other error is a server failure.
+func (cfg *ApiConfig) moderateChirpBody(ctx context.Context, body string) (moderation.Result, error) {
+	if len(body) > maxChirpLength {
+		return moderation.Result{}, errChirpTooLong
+	}
+
+	words, err := cfg.Db.ListBannedWords(ctx)
+	if err != nil {
+		return moderation.Result{}, err
+	}
+	rules := make([]moderation.Rule, len(words))
+	for i, word := range words {
+		rules[i] = moderation.Rule{Word: word.Word, Action: moderation.Action(word.Action)}
+	}
+
+	result := moderation.NewFilter(rules).Check(body)
+	if result.IsRejected() {
+		return result, errChirpRejected
+	}
+	return result, nil
+}
+
+// GetAllChirps answers with a page of chirps, like the timeline and search.
+// Pass next_cursor back as cursor to get the page after it.
+func (cfg *ApiConfig) GetAllChirps(w http.ResponseWriter, r *http.Request) {
 	authorId := r.URL.Query().Get("author_id")
 	sortType := r.URL.Query().Get("sort")
-	var dbChirps []database.Chirp
-	var err error
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		log.Printf("Error parsing pagination parameters: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	author := uuid.NullUUID{}
 	if authorId != "" {
-		id, _ := uuid.Parse(authorId)
-		dbChirps, err = cfg.Db.GetChirpsByUserId(r.Context(), id)
-	} else {
-		dbChirps, err = cfg.Db.GetChirps(r.Context())
+		id, err := uuid.Parse(authorId)
+		if err != nil {
+			log.Printf("Error parsing author_id parameter: %s", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		author = uuid.NullUUID{UUID: id, Valid: true}
 	}
 
-	if sortType == "desc" {
-		sort.Slice(dbChirps, func(i, j int) bool { return dbChirps[i].CreatedAt.After(dbChirps[j].CreatedAt) })
-	} else {
-		sort.Slice(dbChirps, func(i, j int) bool { return dbChirps[i].CreatedAt.Before(dbChirps[j].CreatedAt) })
+	var dbChirps []database.Chirp
+	switch sortType {
+	case "", "asc":
+		dbChirps, err = cfg.Db.ListChirpsAsc(r.Context(), database.ListChirpsAscParams{
+			AuthorID:        author,
+			CursorCreatedAt: page.cursorCreatedAt(),
+			CursorID:        page.cursorID(),
+			Limit:           page.fetchLimit(),
+		})
+	case "desc":
+		dbChirps, err = cfg.Db.ListChirpsDesc(r.Context(), database.ListChirpsDescParams{
+			AuthorID:        author,
+			CursorCreatedAt: page.cursorCreatedAt(),
+			CursorID:        page.cursorID(),
+			Limit:           page.fetchLimit(),
+		})
+	default:
+		log.Printf("Unsupported sort parameter: %s", sortType)
+		w.WriteHeader(http.StatusBadRequest)
+		return
 	}
 
 	if err != nil {
@@ -38,23 +100,17 @@ func (cfg ApiConfig) GetAllChirps(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	chirps := make([]model.Chirp, len(dbChirps))
-	for i, c := range dbChirps {
-		chirps[i].MapDBChirp(c)
-	}
-
-	data, err := json.Marshal(chirps)
-	if err != nil {
-		log.Printf("Error marshalling JSON: %s", err)
-		w.WriteHeader(500)
+	chirpPage := model.NewChirpPage(dbChirps, int(page.Limit))
+	if err := cfg.decorateChirps(r.Context(), chirpPage.Chirps, viewerID(r)); err != nil {
+		log.Printf("Error loading chirp details: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
-	w.Header().Add("Content-type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(data)
+
+	respondWithJSON(w, http.StatusOK, chirpPage)
 }
 
-func (cfg ApiConfig) GetChirpById(w http.ResponseWriter, r *http.Request) {
+func (cfg *ApiConfig) GetChirpById(w http.ResponseWriter, r *http.Request) {
 	w.Header().Add("Content-type", "application/json")
 	chirpId := r.PathValue("chirpID")
 	err := uuid.Validate(chirpId)
@@ -65,15 +121,20 @@ func (cfg ApiConfig) GetChirpById(w http.ResponseWriter, r *http.Request) {
 	}
 	id := uuid.MustParse(r.PathValue("chirpID"))
 	dbChirp, err := cfg.Db.GetChirpById(r.Context(), id)
-	if err != nil {
+	if err != nil || dbChirp.DeletedAt.Valid {
 		log.Printf("Error retriaving chirp : %s", err)
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
-	chirp := model.Chirp{}
-	chirp.MapDBChirp(dbChirp)
-	data, err := json.Marshal(chirp)
+	chirps := make([]model.Chirp, 1)
+	chirps[0].MapDBChirp(dbChirp)
+	if err := cfg.decorateChirps(r.Context(), chirps, viewerID(r)); err != nil {
+		log.Printf("Error loading chirp details: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	data, err := json.Marshal(chirps[0])
 	if err != nil {
 		log.Printf("Error marshalling JSON: %s", err)
 		w.WriteHeader(500)
@@ -83,21 +144,14 @@ func (cfg ApiConfig) GetChirpById(w http.ResponseWriter, r *http.Request) {
 	w.Write(data)
 }
 
-func (cfg ApiConfig) CreateChirp(w http.ResponseWriter, r *http.Request) {
+func (cfg *ApiConfig) CreateChirp(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 	w.Header().Add("Content-type", "application/json")
 
-	token, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		log.Printf("Couldn't find JWT: %s", err)
-		return
-	}
-
-	userId, err := auth.ValidateJWT(token, cfg.JWTSecret)
-	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		log.Printf("Couldn't validate JWT: %s", err)
+	principal := requestPrincipal(r)
+	userId := principal.UserID
+	if cfg.RequireVerifiedEmail && !principal.EmailVerified {
+		respondWithError(w, http.StatusForbidden, "Verify your email address before posting")
 		return
 	}
 
@@ -115,34 +169,106 @@ func (cfg ApiConfig) CreateChirp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if len(newChirp.Body) > 140 {
-		response := model.JsonErrorResponse{Error: "Chirp is too long"}
-		data, err := json.Marshal(response)
-		if err != nil {
-			log.Printf("Error marshalling JSON: %s", err)
-			w.WriteHeader(http.StatusInternalServerError)
+	moderated, err := cfg.moderateChirpBody(r.Context(), newChirp.Body)
+	if errors.Is(err, errChirpTooLong) || errors.Is(err, errChirpRejected) {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Error moderating chirp: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	newChirp.Body = moderated.Body
+
+	inReplyTo := uuid.NullUUID{}
+	if newChirp.InReplyTo != nil {
+		parent, err := cfg.Db.GetChirpById(r.Context(), *newChirp.InReplyTo)
+		if err != nil || parent.DeletedAt.Valid {
+			log.Printf("Error retriaving parent chirp '%s': %s", newChirp.InReplyTo.String(), err)
+			respondWithError(w, http.StatusBadRequest, "Chirp being replied to does not exist")
 			return
 		}
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write(data)
+		inReplyTo = uuid.NullUUID{UUID: parent.ID, Valid: true}
+	}
+
+	mediaIds, err := cfg.validateChirpMedia(r, userId, newChirp.MediaIds)
+	if errors.Is(err, errInvalidMedia) {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Error retriaving chirp media: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	newChirp.Body = util.CleanBody(newChirp.Body)
+	tx, err := cfg.DbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Error starting transaction: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.Db.WithTx(tx)
 
 	chirpParams := database.CreateChirpParams{
-		Body:   newChirp.Body,
-		UserID: userId,
+		Body:      newChirp.Body,
+		UserID:    userId,
+		InReplyTo: inReplyTo,
 	}
-	dbChirp, err := cfg.Db.CreateChirp(r.Context(), chirpParams)
+	dbChirp, err := qtx.CreateChirp(r.Context(), chirpParams)
 	if err != nil {
 		log.Printf("Error creating chirp: %s", err)
 		w.WriteHeader(500)
 		return
 	}
 
-	newChirp.MapDBChirp(dbChirp)
-	data, err := json.Marshal(newChirp)
+	if err := saveChirpEntities(r.Context(), qtx, dbChirp); err != nil {
+		log.Printf("Error indexing hashtags and mentions of chirp '%s': %s", dbChirp.ID.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := flagChirp(r.Context(), qtx, dbChirp.ID, moderated); err != nil {
+		log.Printf("Error flagging chirp '%s' for review: %s", dbChirp.ID.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	for i, mediaId := range mediaIds {
+		err := qtx.AttachChirpMedia(r.Context(), database.AttachChirpMediaParams{
+			ChirpID:  dbChirp.ID,
+			MediaID:  mediaId,
+			Position: int16(i),
+		})
+		if err != nil {
+			log.Printf("Error attaching media '%s' to chirp: %s", mediaId.String(), err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing chirp: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// The chirp is decorated as nobody in particular sees it, since that is
+	// what stream subscribers get. The author's copy only differs in
+	// liked_by_me, and nobody has liked a chirp that was just posted.
+	chirps := make([]model.Chirp, 1)
+	chirps[0].MapDBChirp(dbChirp)
+	if err := cfg.decorateChirps(r.Context(), chirps, uuid.NullUUID{}); err != nil {
+		log.Printf("Error loading chirp details: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	created := chirps[0]
+	likedByMe := false
+	created.LikedByMe = &likedByMe
+	data, err := json.Marshal(created)
 	if err != nil {
 		log.Printf("Error marshalling JSON: %s", err)
 		w.WriteHeader(500)
@@ -150,9 +276,11 @@ func (cfg ApiConfig) CreateChirp(w http.ResponseWriter, r *http.Request) {
 	}
 	w.WriteHeader(http.StatusCreated)
 	w.Write(data)
+
+	cfg.publishChirpEvent(r.Context(), stream.EventChirpCreated, dbChirp.ID, userId, chirps[0])
 }
 
-func (cfg ApiConfig) DeleteChirp(w http.ResponseWriter, r *http.Request) {
+func (cfg *ApiConfig) DeleteChirp(w http.ResponseWriter, r *http.Request) {
 	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
@@ -160,22 +288,127 @@ func (cfg ApiConfig) DeleteChirp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := auth.GetBearerToken(r.Header)
+	userId := requestPrincipal(r).UserID
+
+	tx, err := cfg.DbConn.BeginTx(r.Context(), nil)
 	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		log.Printf("Couldn't find JWT: %s", err)
+		log.Printf("Error starting transaction: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.Db.WithTx(tx)
+
+	dbChirp, err := qtx.GetChirpByIdForUpdate(r.Context(), chirpID)
+	if err != nil || dbChirp.DeletedAt.Valid {
+		log.Printf("Error retriaving chirp '%s': %s", chirpID.String(), err)
+		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
-	userId, err := auth.ValidateJWT(token, cfg.JWTSecret)
+	if dbChirp.UserID != userId {
+		log.Printf("User Id from Chirp and user id from token are not the same")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	// Chirps with replies are blanked out instead of removed so the
+	// conversation below them keeps its shape.
+	hasReplies, err := qtx.ChirpHasReplies(r.Context(), chirpID)
 	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		log.Printf("Couldn't validate JWT: %s", err)
+		log.Printf("Error checking replies of chirp '%s': %s", chirpID.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	dbChirp, err := cfg.Db.GetChirpById(r.Context(), chirpID)
+	// Media that is only attached here goes with the chirp, tombstone or not.
+	mediaKeys, err := qtx.DeleteChirpMedia(r.Context(), chirpID)
+	if err != nil {
+		log.Printf("Error deleting media of chirp '%s': %s", chirpID.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if hasReplies {
+		err = qtx.DeleteChirpRevisions(r.Context(), chirpID)
+		if err == nil {
+			err = qtx.DeleteChirpHashtags(r.Context(), chirpID)
+		}
+		if err == nil {
+			err = qtx.DeleteChirpMentions(r.Context(), chirpID)
+		}
+		if err == nil {
+			err = qtx.TombstoneChirp(r.Context(), chirpID)
+		}
+	} else {
+		err = qtx.DeleteChirp(r.Context(), chirpID)
+	}
+	if err != nil {
+		log.Printf("Couldn't delete chirp '%s': %s", chirpID.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing deletion of chirp '%s': %s", chirpID.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	cfg.deleteBlobs(r.Context(), mediaKeys)
+
+	w.WriteHeader(http.StatusNoContent)
+
+	cfg.publishChirpEvent(r.Context(), stream.EventChirpDeleted, chirpID, userId, chirpDeletedEvent{
+		Id:     chirpID,
+		UserId: userId,
+	})
+}
+
+func (cfg *ApiConfig) EditChirp(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		log.Printf("Error parsing chirpId parameter: %s", err)
+		return
+	}
+
+	userId := requestPrincipal(r).UserID
+
+	type parameters struct {
+		Body string `json:"body"`
+	}
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		log.Printf("Error decoding JSON: %s", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	moderated, err := cfg.moderateChirpBody(r.Context(), params.Body)
+	if errors.Is(err, errChirpTooLong) || errors.Is(err, errChirpRejected) {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Error moderating chirp: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	body := moderated.Body
+
+	tx, err := cfg.DbConn.BeginTx(r.Context(), nil)
 	if err != nil {
+		log.Printf("Error starting transaction: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.Db.WithTx(tx)
+
+	dbChirp, err := qtx.GetChirpByIdForUpdate(r.Context(), chirpID)
+	if err != nil || dbChirp.DeletedAt.Valid {
 		log.Printf("Error retriaving chirp '%s': %s", chirpID.String(), err)
 		w.WriteHeader(http.StatusNotFound)
 		return
@@ -187,14 +420,290 @@ func (cfg ApiConfig) DeleteChirp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = cfg.Db.DeleteChirp(r.Context(), chirpID)
+	if dbChirp.Body != body {
+		err = qtx.CreateChirpRevision(r.Context(), database.CreateChirpRevisionParams{
+			ChirpID: dbChirp.ID,
+			Body:    dbChirp.Body,
+		})
+		if err != nil {
+			log.Printf("Error saving revision of chirp '%s': %s", chirpID.String(), err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		dbChirp, err = qtx.UpdateChirpBody(r.Context(), database.UpdateChirpBodyParams{
+			ID:   dbChirp.ID,
+			Body: body,
+		})
+		if err != nil {
+			log.Printf("Error updating chirp '%s': %s", chirpID.String(), err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if err := saveChirpEntities(r.Context(), qtx, dbChirp); err != nil {
+			log.Printf("Error indexing hashtags and mentions of chirp '%s': %s", chirpID.String(), err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if err := flagChirp(r.Context(), qtx, chirpID, moderated); err != nil {
+			log.Printf("Error flagging chirp '%s' for review: %s", chirpID.String(), err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing edit of chirp '%s': %s", chirpID.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	chirps := make([]model.Chirp, 1)
+	chirps[0].MapDBChirp(dbChirp)
+	if err := cfg.decorateChirps(r.Context(), chirps, uuid.NullUUID{UUID: userId, Valid: true}); err != nil {
+		log.Printf("Error loading chirp details: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, chirps[0])
+}
+
+func (cfg *ApiConfig) GetChirpRevisions(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
 	if err != nil {
-		log.Printf("Couldn't delete chirp '%s': %s", chirpID.String(), err)
+		w.WriteHeader(http.StatusBadRequest)
+		log.Printf("Error parsing chirpId parameter: %s", err)
+		return
+	}
+
+	if dbChirp, err := cfg.Db.GetChirpById(r.Context(), chirpID); err != nil || dbChirp.DeletedAt.Valid {
+		log.Printf("Error retriaving chirp '%s': %s", chirpID.String(), err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	dbRevisions, err := cfg.Db.GetChirpRevisions(r.Context(), chirpID)
+	if err != nil {
+		log.Printf("Error retriaving revisions of chirp '%s': %s", chirpID.String(), err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	revisions := make([]model.ChirpRevision, len(dbRevisions))
+	for i, rev := range dbRevisions {
+		revisions[i].MapDBChirpRevision(rev)
+	}
+	respondWithJSON(w, http.StatusOK, revisions)
+}
+
+func (cfg *ApiConfig) GetChirpThread(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		log.Printf("Error parsing chirpId parameter: %s", err)
+		return
+	}
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		log.Printf("Error parsing pagination parameters: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	dbChirp, err := cfg.Db.GetChirpById(r.Context(), chirpID)
+	if err != nil {
+		log.Printf("Error retriaving chirp '%s': %s", chirpID.String(), err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	dbAncestors, err := cfg.Db.GetChirpAncestors(r.Context(), chirpID)
+	if err != nil {
+		log.Printf("Error retriaving ancestors of chirp '%s': %s", chirpID.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	dbDescendants, err := cfg.Db.GetChirpDescendants(r.Context(), database.GetChirpDescendantsParams{
+		ChirpID:         chirpID,
+		CursorCreatedAt: page.cursorCreatedAt(),
+		CursorID:        page.cursorID(),
+		Limit:           page.fetchLimit(),
+	})
+	if err != nil {
+		log.Printf("Error retriaving replies of chirp '%s': %s", chirpID.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// The root chirp travels with the ancestors so that a single call
+	// decorates the whole thread.
+	thread := model.ChirpThread{}
+	chirps := make([]model.Chirp, len(dbAncestors)+1)
+	for i, c := range dbAncestors {
+		chirps[i].MapDBChirp(database.Chirp(c))
+	}
+	chirps[len(dbAncestors)].MapDBChirp(dbChirp)
+
+	descendants := make([]database.Chirp, len(dbDescendants))
+	for i, c := range dbDescendants {
+		descendants[i] = database.Chirp(c)
+	}
+	replies := model.NewChirpPage(descendants, int(page.Limit))
+
+	chirps = append(chirps, replies.Chirps...)
+	if err := cfg.decorateChirps(r.Context(), chirps, viewerID(r)); err != nil {
+		log.Printf("Error loading chirp details: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	thread.Ancestors = chirps[:len(dbAncestors)]
+	thread.Chirp = chirps[len(dbAncestors)]
+	thread.Replies = chirps[len(dbAncestors)+1:]
+	thread.NextCursor = replies.NextCursor
+	respondWithJSON(w, http.StatusOK, thread)
+}
+
+// saveChirpEntities replaces the hashtag and mention index of a chirp with
+// what is currently in its body.
+func saveChirpEntities(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	found := entities.Parse(chirp.Body)
+
+	if err := q.DeleteChirpHashtags(ctx, chirp.ID); err != nil {
+		return err
+	}
+	if tags := entities.Values(found, entities.TypeHashtag); len(tags) > 0 {
+		err := q.AddChirpHashtags(ctx, database.AddChirpHashtagsParams{
+			ChirpID:   chirp.ID,
+			Tags:      tags,
+			CreatedAt: chirp.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := q.DeleteChirpMentions(ctx, chirp.ID); err != nil {
+		return err
+	}
+	if handles := entities.Values(found, entities.TypeMention); len(handles) > 0 {
+		err := q.AddChirpMentions(ctx, database.AddChirpMentionsParams{
+			ChirpID:   chirp.ID,
+			Handles:   handles,
+			CreatedAt: chirp.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flagChirp queues a chirp for moderator review when its body matched
+// words with the flag action.
+func flagChirp(ctx context.Context, q *database.Queries, chirpID uuid.UUID, moderated moderation.Result) error {
+	if !moderated.IsFlagged() {
+		return nil
+	}
+	return q.FlagChirp(ctx, database.FlagChirpParams{
+		ChirpID: chirpID,
+		Words:   moderated.Flagged,
+	})
+}
+
+// viewerID is the caller on routes behind OptionalAuth, if there is one.
+func viewerID(r *http.Request) uuid.NullUUID {
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: principal.UserID, Valid: true}
+}
+
+// decorateChirps fills in the per-chirp aggregates that are not stored on
+// the chirps row itself. liked_by_me is only set when viewer is known.
+func (cfg *ApiConfig) decorateChirps(ctx context.Context, chirps []model.Chirp, viewer uuid.NullUUID) error {
+	if len(chirps) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(chirps))
+	for i, c := range chirps {
+		ids[i] = c.Id
+	}
+
+	replyCounts, err := cfg.Db.CountRepliesForChirps(ctx, ids)
+	if err != nil {
+		return err
+	}
+	replies := make(map[uuid.UUID]int64, len(replyCounts))
+	for _, c := range replyCounts {
+		replies[c.InReplyTo.UUID] = c.ReplyCount
+	}
+
+	likeCounts, err := cfg.Db.CountLikesForChirps(ctx, ids)
+	if err != nil {
+		return err
+	}
+	likes := make(map[uuid.UUID]int64, len(likeCounts))
+	for _, c := range likeCounts {
+		likes[c.ChirpID] = c.LikeCount
+	}
+
+	var liked map[uuid.UUID]bool
+	if viewer.Valid {
+		likedIds, err := cfg.Db.GetLikedChirpIds(ctx, database.GetLikedChirpIdsParams{
+			UserID:   viewer.UUID,
+			ChirpIds: ids,
+		})
+		if err != nil {
+			return err
+		}
+		liked = make(map[uuid.UUID]bool, len(likedIds))
+		for _, id := range likedIds {
+			liked[id] = true
+		}
+	}
+
+	mediaRows, err := cfg.Db.GetMediaForChirps(ctx, ids)
+	if err != nil {
+		return err
+	}
+	attached := make(map[uuid.UUID][]model.Media, len(mediaRows))
+	for _, row := range mediaRows {
+		m := model.Media{}
+		m.MapDBMedia(database.Media{
+			ID:          row.ID,
+			CreatedAt:   row.CreatedAt,
+			UserID:      row.UserID,
+			StorageKey:  row.StorageKey,
+			ContentType: row.ContentType,
+			SizeBytes:   row.SizeBytes,
+		}, cfg.Blobs.URL(row.StorageKey))
+		attached[row.ChirpID] = append(attached[row.ChirpID], m)
+	}
+
+	for i := range chirps {
+		chirps[i].ReplyCount = replies[chirps[i].Id]
+		chirps[i].LikeCount = likes[chirps[i].Id]
+		chirps[i].Media = attached[chirps[i].Id]
+		if chirps[i].Media == nil {
+			chirps[i].Media = []model.Media{}
+		}
+		chirps[i].MediaIds = make([]uuid.UUID, len(chirps[i].Media))
+		for j, m := range chirps[i].Media {
+			chirps[i].MediaIds[j] = m.Id
+		}
+		if viewer.Valid {
+			likedByMe := liked[chirps[i].Id]
+			chirps[i].LikedByMe = &likedByMe
+		}
+	}
+	return nil
 }
 
 func Healthz(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/JosueAD95/Server-course/internal/auth"
+	database "github.com/JosueAD95/Server-course/internal/database"
+	"github.com/JosueAD95/Server-course/internal/mailer"
+)
+
+const (
+	emailVerificationTokenLifetime = 24 * time.Hour
+	securityEventEmailChanged      = "email_changed"
+)
+
+// sendEmailVerification mails a link proving the user owns email, which is
+// either their current address or the one they asked to change to.
+func (cfg *ApiConfig) sendEmailVerification(ctx context.Context, userId uuid.UUID, email string) error {
+	token := auth.MakeRefreshToken()
+	err := cfg.Db.CreateEmailVerificationToken(ctx, database.CreateEmailVerificationTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    userId,
+		Email:     email,
+		ExpiresAt: time.Now().Add(emailVerificationTokenLifetime),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := cfg.BaseURL + "/app/verify-email/?token=" + url.QueryEscape(token)
+	return cfg.Mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Confirm your email address for Chirpy",
+		Body: fmt.Sprintf(`To confirm that this is your email address, open this link within a day:
+
+%s
+
+If you didn't sign up for Chirpy or change your email there, ignore this email.
+`, link),
+	})
+}
+
+// sendEmailVerificationLater queues the verification mail rather than
+// holding up the response.
+func (cfg *ApiConfig) sendEmailVerificationLater(userId uuid.UUID, email string) {
+	queued := cfg.MailQueue.Enqueue(func(ctx context.Context) {
+		if err := cfg.sendEmailVerification(ctx, userId, email); err != nil {
+			log.Printf("Error sending email verification to (%s): %s", email, err)
+		}
+	})
+	if !queued {
+		log.Printf("Dropped email verification to (%s): mail queue is full", email)
+	}
+}
+
+// VerifyEmail confirms an address with a token from sendEmailVerification.
+// For a pending change, this is when the new address replaces the old one.
+func (cfg *ApiConfig) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	type parameters struct {
+		Token string `json:"token"`
+	}
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		log.Printf("Error decoding JSON: %s", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	tx, err := cfg.DbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Error starting transaction: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.Db.WithTx(tx)
+
+	verification, err := qtx.UseEmailVerificationToken(r.Context(), auth.HashToken(params.Token))
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusBadRequest, "Invalid or expired verification token")
+		return
+	}
+	if err != nil {
+		log.Printf("Error using email verification token: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	user, err := qtx.GetUserById(r.Context(), verification.UserID)
+	if err != nil {
+		log.Printf("Error retriaving user '%s': %s", verification.UserID.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// The token is for an address that is neither the current nor the
+	// pending one when the user has asked for another change since.
+	verified, err := qtx.VerifyUserEmail(r.Context(), database.VerifyUserEmailParams{
+		ID:    verification.UserID,
+		Email: verification.Email,
+	})
+	if isUniqueViolation(err) {
+		respondWithError(w, http.StatusConflict, "Email is already in use")
+		return
+	}
+	if err != nil {
+		log.Printf("Error verifying email of user '%s': %s", verification.UserID.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if verified == 0 {
+		respondWithError(w, http.StatusBadRequest, "Invalid or expired verification token")
+		return
+	}
+
+	// Reset links sent to the old address must not outlive the change.
+	if user.Email != verification.Email {
+		err = qtx.InvalidatePasswordResetTokens(r.Context(), user.ID)
+		if err == nil {
+			err = qtx.CreateSecurityEvent(r.Context(), database.CreateSecurityEventParams{
+				UserID:    user.ID,
+				EventType: securityEventEmailChanged,
+				Details:   fmt.Sprintf("email changed from %s to %s", user.Email, verification.Email),
+			})
+		}
+		if err != nil {
+			log.Printf("Error recording email change of user '%s': %s", user.ID.String(), err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing email verification: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ResendEmailVerification sends a new link for the pending address, or for
+// the current one if it hasn't been verified yet.
+func (cfg *ApiConfig) ResendEmailVerification(w http.ResponseWriter, r *http.Request) {
+	userId := requestPrincipal(r).UserID
+
+	user, err := cfg.Db.GetUserById(r.Context(), userId)
+	if err != nil {
+		log.Printf("Error retriaving user '%s': %s", userId.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	email := user.PendingEmail.String
+	if !user.PendingEmail.Valid {
+		if user.EmailVerifiedAt.Valid {
+			respondWithError(w, http.StatusConflict, "Email is already verified")
+			return
+		}
+		email = user.Email
+	}
+
+	cfg.sendEmailVerificationLater(userId, email)
+	w.WriteHeader(http.StatusAccepted)
+}
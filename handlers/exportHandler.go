@@ -0,0 +1,242 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/JosueAD95/Server-course/internal/auth"
+	database "github.com/JosueAD95/Server-course/internal/database"
+	"github.com/JosueAD95/Server-course/internal/export"
+	model "github.com/JosueAD95/Server-course/models"
+)
+
+const (
+	dataExportPending = "pending"
+	dataExportReady   = "ready"
+
+	// dataExportTimeout is how long an archive may take to build. A pending
+	// export older than that was cut short, by a restart say, and is
+	// started over on the next request.
+	dataExportTimeout      = time.Hour
+	dataExportLifetime     = 7 * 24 * time.Hour
+	dataExportLinkLifetime = 15 * time.Minute
+)
+
+// ExportUserData reports on the caller's latest data export, starting a new
+// one when there is none that is ready or still being built. A ready export
+// comes with a signed download link that is good for a few minutes; ask
+// again for a fresh one.
+func (cfg *ApiConfig) ExportUserData(w http.ResponseWriter, r *http.Request) {
+	userId := requestPrincipal(r).UserID
+
+	latest, err := cfg.Db.GetLatestDataExport(r.Context(), userId)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Error retriaving data export of user '%s': %s", userId.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	resp := model.DataExport{}
+	switch {
+	case err == nil && latest.Status == dataExportReady && latest.ExpiresAt.Time.After(time.Now()):
+		resp.MapDBDataExport(latest, cfg.dataExportLink(latest))
+		respondWithJSON(w, http.StatusOK, resp)
+		return
+	case err == nil && latest.Status == dataExportPending && time.Since(latest.CreatedAt) < dataExportTimeout:
+		resp.MapDBDataExport(latest, "")
+		respondWithJSON(w, http.StatusAccepted, resp)
+		return
+	}
+
+	dbExport, err := cfg.Db.CreateDataExport(r.Context(), userId)
+	if err != nil {
+		log.Printf("Error creating data export of user '%s': %s", userId.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), dataExportTimeout)
+	go func() {
+		defer cancel()
+		if err := cfg.buildDataExport(ctx, dbExport); err != nil {
+			log.Printf("Error building data export '%s': %s", dbExport.ID.String(), err)
+			if err := cfg.Db.FailDataExport(context.WithoutCancel(ctx), dbExport.ID); err != nil {
+				log.Printf("Error marking data export '%s' failed: %s", dbExport.ID.String(), err)
+			}
+		}
+	}()
+
+	resp.MapDBDataExport(dbExport, "")
+	respondWithJSON(w, http.StatusAccepted, resp)
+}
+
+// buildDataExport writes the user's profile, chirps, likes and sessions to
+// a ZIP archive and marks the export ready.
+func (cfg *ApiConfig) buildDataExport(ctx context.Context, dbExport database.DataExport) error {
+	userId := dbExport.UserID
+
+	dbUser, err := cfg.Db.GetUserById(ctx, userId)
+	if err != nil {
+		return err
+	}
+	profile := model.User{}
+	profile.MapDbUser(dbUser)
+
+	dbChirps, err := cfg.Db.GetChirpsByUserId(ctx, userId)
+	if err != nil {
+		return err
+	}
+	chirps := make([]model.Chirp, len(dbChirps))
+	for i, dbChirp := range dbChirps {
+		chirps[i].MapDBChirp(dbChirp)
+	}
+
+	type like struct {
+		ChirpId uuid.UUID `json:"chirp_id"`
+		LikedAt time.Time `json:"liked_at"`
+	}
+	dbLikes, err := cfg.Db.ListUserLikes(ctx, userId)
+	if err != nil {
+		return err
+	}
+	likes := make([]like, len(dbLikes))
+	for i, dbLike := range dbLikes {
+		likes[i] = like{ChirpId: dbLike.ChirpID, LikedAt: dbLike.CreatedAt}
+	}
+
+	dbSessions, err := cfg.Db.ListUserSessions(ctx, userId)
+	if err != nil {
+		return err
+	}
+	sessions := make([]model.Session, len(dbSessions))
+	for i, dbSession := range dbSessions {
+		sessions[i].MapDBSession(dbSession)
+	}
+
+	var archive bytes.Buffer
+	err = export.WriteZip(&archive, []export.File{
+		{Name: "profile.json", Data: profile},
+		{Name: "chirps.json", Data: chirps},
+		{Name: "likes.json", Data: likes},
+		{Name: "sessions.json", Data: sessions},
+	})
+	if err != nil {
+		return err
+	}
+
+	// The key is random and the store isn't served, so the file can only be
+	// reached through a signed link to DownloadDataExport.
+	key := auth.MakeRefreshToken() + ".zip"
+	if err := cfg.Exports.Put(ctx, key, &archive); err != nil {
+		return err
+	}
+	err = cfg.Db.CompleteDataExport(ctx, database.CompleteDataExportParams{
+		ID:         dbExport.ID,
+		StorageKey: sql.NullString{String: key, Valid: true},
+		ExpiresAt:  sql.NullTime{Time: time.Now().Add(dataExportLifetime), Valid: true},
+	})
+	if err != nil {
+		cfg.Exports.Delete(context.WithoutCancel(ctx), key)
+		return err
+	}
+	return nil
+}
+
+// dataExportLink signs a download link for a ready export. It expires with
+// the export if that comes first.
+func (cfg *ApiConfig) dataExportLink(dbExport database.DataExport) string {
+	expires := time.Now().Add(dataExportLinkLifetime)
+	if dbExport.ExpiresAt.Time.Before(expires) {
+		expires = dbExport.ExpiresAt.Time
+	}
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires.Unix(), 10))
+	query.Set("signature", cfg.ExportLinks.Sign(dbExport.ID, expires))
+	return cfg.BaseURL + "/api/exports/" + dbExport.ID.String() + "?" + query.Encode()
+}
+
+// DownloadDataExport serves the archive of a link from dataExportLink. The
+// signature stands in for a session, so browsers can follow the link.
+func (cfg *ApiConfig) DownloadDataExport(w http.ResponseWriter, r *http.Request) {
+	exportId, err := uuid.Parse(r.PathValue("exportID"))
+	if err != nil {
+		log.Printf("Error parsing exportID parameter: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	expires, err := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+	if err != nil {
+		respondWithError(w, http.StatusForbidden, "Invalid download link")
+		return
+	}
+	err = cfg.ExportLinks.Verify(exportId, expires, r.URL.Query().Get("signature"), time.Now())
+	if errors.Is(err, export.ErrLinkExpired) {
+		respondWithError(w, http.StatusGone, "Download link has expired, ask for a new one")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusForbidden, "Invalid download link")
+		return
+	}
+
+	dbExport, err := cfg.Db.GetDataExport(r.Context(), exportId)
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error retriaving data export '%s': %s", exportId.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if dbExport.Status != dataExportReady || !dbExport.ExpiresAt.Time.After(time.Now()) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	archive, err := cfg.Exports.Open(r.Context(), dbExport.StorageKey.String)
+	if err != nil {
+		log.Printf("Error opening data export '%s': %s", exportId.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer archive.Close()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf(`attachment; filename="chirpy-export-%s.zip"`, dbExport.CreatedAt.Format("2006-01-02")))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, archive); err != nil {
+		log.Printf("Error sending data export '%s': %s", exportId.String(), err)
+	}
+}
+
+// DeleteStaleDataExports removes expired archives, and the records of
+// exports that failed or never finished.
+func (cfg *ApiConfig) DeleteStaleDataExports(ctx context.Context) error {
+	keys, err := cfg.Db.DeleteStaleDataExports(ctx, time.Now().Add(-dataExportTimeout))
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if !key.Valid {
+			continue
+		}
+		if err := cfg.Exports.Delete(ctx, key.String); err != nil {
+			log.Printf("Error deleting data export file '%s': %s", key.String, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	database "github.com/JosueAD95/Server-course/internal/database"
+	model "github.com/JosueAD95/Server-course/models"
+)
+
+func (cfg *ApiConfig) FollowUser(w http.ResponseWriter, r *http.Request) {
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		log.Printf("Error parsing userID parameter: %s", err)
+		return
+	}
+
+	userId := requestPrincipal(r).UserID
+
+	if followeeID == userId {
+		respondWithError(w, http.StatusBadRequest, "You can't follow yourself")
+		return
+	}
+
+	if _, err := cfg.Db.GetUserById(r.Context(), followeeID); err != nil {
+		log.Printf("Error retriaving user '%s': %s", followeeID.String(), err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	err = cfg.Db.FollowUser(r.Context(), database.FollowUserParams{
+		FollowerID: userId,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		log.Printf("Error following user '%s': %s", followeeID.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *ApiConfig) UnfollowUser(w http.ResponseWriter, r *http.Request) {
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		log.Printf("Error parsing userID parameter: %s", err)
+		return
+	}
+
+	userId := requestPrincipal(r).UserID
+
+	err = cfg.Db.UnfollowUser(r.Context(), database.UnfollowUserParams{
+		FollowerID: userId,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		log.Printf("Error unfollowing user '%s': %s", followeeID.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *ApiConfig) GetFollowers(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		log.Printf("Error parsing userID parameter: %s", err)
+		return
+	}
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		log.Printf("Error parsing pagination parameters: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	rows, err := cfg.Db.GetFollowers(r.Context(), database.GetFollowersParams{
+		FolloweeID:      userID,
+		CursorCreatedAt: page.cursorCreatedAt(),
+		CursorID:        page.cursorID(),
+		Limit:           page.fetchLimit(),
+	})
+	if err != nil {
+		log.Printf("Error retriaving followers of user '%s': %s", userID.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	followers := make([]model.Follow, len(rows))
+	for i, row := range rows {
+		followers[i] = model.Follow{UserId: row.UserID, FollowedAt: row.CreatedAt}
+	}
+	respondWithJSON(w, http.StatusOK, model.NewFollowPage(followers, int(page.Limit)))
+}
+
+func (cfg *ApiConfig) GetFollowing(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		log.Printf("Error parsing userID parameter: %s", err)
+		return
+	}
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		log.Printf("Error parsing pagination parameters: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	rows, err := cfg.Db.GetFollowing(r.Context(), database.GetFollowingParams{
+		FollowerID:      userID,
+		CursorCreatedAt: page.cursorCreatedAt(),
+		CursorID:        page.cursorID(),
+		Limit:           page.fetchLimit(),
+	})
+	if err != nil {
+		log.Printf("Error retriaving users followed by '%s': %s", userID.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	following := make([]model.Follow, len(rows))
+	for i, row := range rows {
+		following[i] = model.Follow{UserId: row.UserID, FollowedAt: row.CreatedAt}
+	}
+	respondWithJSON(w, http.StatusOK, model.NewFollowPage(following, int(page.Limit)))
+}
+
+func (cfg *ApiConfig) GetTimeline(w http.ResponseWriter, r *http.Request) {
+	userId := requestPrincipal(r).UserID
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		log.Printf("Error parsing pagination parameters: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	dbChirps, err := cfg.Db.GetTimeline(r.Context(), database.GetTimelineParams{
+		FollowerID:      userId,
+		CursorCreatedAt: page.cursorCreatedAt(),
+		CursorID:        page.cursorID(),
+		Limit:           page.fetchLimit(),
+	})
+	if err != nil {
+		log.Printf("Error retriaving timeline of user '%s': %s", userId.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	chirpPage := model.NewChirpPage(dbChirps, int(page.Limit))
+	if err := cfg.decorateChirps(r.Context(), chirpPage.Chirps, uuid.NullUUID{UUID: userId, Valid: true}); err != nil {
+		log.Printf("Error loading chirp details: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, chirpPage)
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	database "github.com/JosueAD95/Server-course/internal/database"
+	"github.com/JosueAD95/Server-course/internal/entities"
+	model "github.com/JosueAD95/Server-course/models"
+)
+
+const (
+	defaultTrendingWindow = 24 * time.Hour
+	maxTrendingWindow     = 30 * 24 * time.Hour
+	defaultTrendingLimit  = 10
+)
+
+func (cfg *ApiConfig) GetChirpsByHashtag(w http.ResponseWriter, r *http.Request) {
+	tag := entities.Normalize(r.PathValue("tag"))
+	if tag == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		log.Printf("Error parsing pagination parameters: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	dbChirps, err := cfg.Db.GetChirpsByHashtag(r.Context(), database.GetChirpsByHashtagParams{
+		Tag:             tag,
+		CursorCreatedAt: page.cursorCreatedAt(),
+		CursorID:        page.cursorID(),
+		Limit:           page.fetchLimit(),
+	})
+	if err != nil {
+		log.Printf("Error retriaving chirps tagged '%s': %s", tag, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	chirpPage := model.NewChirpPage(dbChirps, int(page.Limit))
+	if err := cfg.decorateChirps(r.Context(), chirpPage.Chirps, viewerID(r)); err != nil {
+		log.Printf("Error loading chirp details: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, chirpPage)
+}
+
+// GetTrendingHashtags ranks hashtags by how many chirps used them within
+// the sliding window ending now, e.g. ?window=6h.
+func (cfg *ApiConfig) GetTrendingHashtags(w http.ResponseWriter, r *http.Request) {
+	window := defaultTrendingWindow
+	if value := r.URL.Query().Get("window"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 || d > maxTrendingWindow {
+			respondWithError(w, http.StatusBadRequest, "window must be a duration between 1s and 720h")
+			return
+		}
+		window = d
+	}
+
+	limit := defaultTrendingLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxPageSize {
+			respondWithError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxPageSize))
+			return
+		}
+		limit = n
+	}
+
+	rows, err := cfg.Db.GetTrendingHashtags(r.Context(), database.GetTrendingHashtagsParams{
+		Since: time.Now().UTC().Add(-window),
+		Limit: int32(limit),
+	})
+	if err != nil {
+		log.Printf("Error retriaving trending hashtags: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	trending := make([]model.TrendingHashtag, len(rows))
+	for i, row := range rows {
+		trending[i] = model.TrendingHashtag{Tag: row.Tag, ChirpCount: row.ChirpCount}
+	}
+	respondWithJSON(w, http.StatusOK, trending)
+}
@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	model "github.com/JosueAD95/Server-course/models"
+)
+
+func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Error marshalling JSON: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-type", "application/json")
+	w.WriteHeader(code)
+	w.Write(data)
+}
+
+func respondWithError(w http.ResponseWriter, code int, msg string) {
+	respondWithJSON(w, code, model.JsonErrorResponse{Error: msg})
+}
@@ -0,0 +1,12 @@
+package handler
+
+import (
+	"net/http"
+)
+
+// JWKS publishes the public keys access tokens can be verified with, so
+// other services don't need any of our secrets.
+func (cfg *ApiConfig) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	respondWithJSON(w, http.StatusOK, cfg.JWTKeys.JWKS())
+}
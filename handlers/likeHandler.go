@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	database "github.com/JosueAD95/Server-course/internal/database"
+	model "github.com/JosueAD95/Server-course/models"
+	util "github.com/JosueAD95/Server-course/utils"
+)
+
+func (cfg *ApiConfig) LikeChirp(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		log.Printf("Error parsing chirpId parameter: %s", err)
+		return
+	}
+
+	userId := requestPrincipal(r).UserID
+
+	dbChirp, err := cfg.Db.GetChirpById(r.Context(), chirpID)
+	if err != nil || dbChirp.DeletedAt.Valid {
+		log.Printf("Error retriaving chirp '%s': %s", chirpID.String(), err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	err = cfg.Db.LikeChirp(r.Context(), database.LikeChirpParams{
+		ChirpID: chirpID,
+		UserID:  userId,
+	})
+	if err != nil {
+		log.Printf("Error liking chirp '%s': %s", chirpID.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *ApiConfig) UnlikeChirp(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		log.Printf("Error parsing chirpId parameter: %s", err)
+		return
+	}
+
+	userId := requestPrincipal(r).UserID
+
+	err = cfg.Db.UnlikeChirp(r.Context(), database.UnlikeChirpParams{
+		ChirpID: chirpID,
+		UserID:  userId,
+	})
+	if err != nil {
+		log.Printf("Error unliking chirp '%s': %s", chirpID.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *ApiConfig) GetUserLikes(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		log.Printf("Error parsing userID parameter: %s", err)
+		return
+	}
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		log.Printf("Error parsing pagination parameters: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	rows, err := cfg.Db.GetChirpsLikedByUser(r.Context(), database.GetChirpsLikedByUserParams{
+		UserID:          userID,
+		CursorCreatedAt: page.cursorCreatedAt(),
+		CursorID:        page.cursorID(),
+		Limit:           page.fetchLimit(),
+	})
+	if err != nil {
+		log.Printf("Error retriaving likes of user '%s': %s", userID.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// The page is ordered by when the like happened, so the cursor is
+	// built from liked_at rather than the chirp's own timestamp.
+	chirpPage := model.ChirpPage{}
+	if len(rows) > int(page.Limit) {
+		rows = rows[:page.Limit]
+		last := rows[len(rows)-1]
+		chirpPage.NextCursor = util.EncodeCursor(util.Cursor{CreatedAt: last.LikedAt, ID: last.ID})
+	}
+	chirpPage.Chirps = make([]model.Chirp, len(rows))
+	for i, row := range rows {
+		chirpPage.Chirps[i].MapDBChirp(database.Chirp{
+			ID:        row.ID,
+			CreatedAt: row.CreatedAt,
+			UpdatedAt: row.UpdatedAt,
+			Body:      row.Body,
+			UserID:    row.UserID,
+			InReplyTo: row.InReplyTo,
+			DeletedAt: row.DeletedAt,
+		})
+	}
+
+	if err := cfg.decorateChirps(r.Context(), chirpPage.Chirps, viewerID(r)); err != nil {
+		log.Printf("Error loading chirp details: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, chirpPage)
+}
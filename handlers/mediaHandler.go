@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	database "github.com/JosueAD95/Server-course/internal/database"
+	"github.com/JosueAD95/Server-course/internal/media"
+	model "github.com/JosueAD95/Server-course/models"
+)
+
+const (
+	maxUploadSize     = 5 << 20
+	maxMediaPerChirp  = 4
+	sniffContentBytes = 512
+
+	// Uploads get this long to be attached to a chirp before they are
+	// deleted, and are deleted this many at a time.
+	unattachedMediaRetention = 24 * time.Hour
+	unattachedMediaBatchSize = 100
+)
+
+func (cfg *ApiConfig) UploadMedia(w http.ResponseWriter, r *http.Request) {
+	userId := requestPrincipal(r).UserID
+
+	// Leave some room for the multipart framing around the file itself.
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize+(64<<10))
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+		log.Printf("Error parsing multipart form: %s", err)
+		respondWithError(w, http.StatusRequestEntityTooLarge, "Upload must be a multipart form of at most 5MB")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		log.Printf("Error reading uploaded file: %s", err)
+		respondWithError(w, http.StatusBadRequest, "Missing file field")
+		return
+	}
+	defer file.Close()
+
+	if header.Size > maxUploadSize {
+		respondWithError(w, http.StatusRequestEntityTooLarge, "File must be at most 5MB")
+		return
+	}
+
+	head := make([]byte, sniffContentBytes)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		log.Printf("Error reading uploaded file: %s", err)
+		respondWithError(w, http.StatusBadRequest, "Empty file")
+		return
+	}
+	head = head[:n]
+
+	contentType, ext, err := media.DetectContentType(head)
+	if err != nil {
+		respondWithError(w, http.StatusUnsupportedMediaType, "File must be a PNG, JPEG, GIF or WebP image")
+		return
+	}
+
+	id := uuid.New()
+	key := id.String() + ext
+	if err := cfg.Blobs.Put(r.Context(), key, io.MultiReader(bytes.NewReader(head), file)); err != nil {
+		log.Printf("Error storing media '%s': %s", key, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	dbMedia, err := cfg.Db.CreateMedia(r.Context(), database.CreateMediaParams{
+		ID:          id,
+		UserID:      userId,
+		StorageKey:  key,
+		ContentType: contentType,
+		SizeBytes:   header.Size,
+	})
+	if err != nil {
+		log.Printf("Error saving media '%s': %s", key, err)
+		if err := cfg.Blobs.Delete(r.Context(), key); err != nil {
+			log.Printf("Error removing orphaned media '%s': %s", key, err)
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	uploaded := model.Media{}
+	uploaded.MapDBMedia(dbMedia, cfg.Blobs.URL(dbMedia.StorageKey))
+	respondWithJSON(w, http.StatusCreated, uploaded)
+}
+
+var errInvalidMedia = errors.New("media_ids must reference up to 4 of your own uploads")
+
+// validateChirpMedia checks that every referenced upload exists and belongs
+// to the author. The returned IDs are deduplicated and keep request order.
+func (cfg *ApiConfig) validateChirpMedia(r *http.Request, userId uuid.UUID, ids []uuid.UUID) ([]uuid.UUID, error) {
+	unique := make([]uuid.UUID, 0, len(ids))
+	seen := map[uuid.UUID]bool{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	if len(unique) == 0 {
+		return unique, nil
+	}
+	if len(unique) > maxMediaPerChirp {
+		return nil, errInvalidMedia
+	}
+
+	found, err := cfg.Db.GetMediaByIds(r.Context(), unique)
+	if err != nil {
+		return nil, err
+	}
+	if len(found) != len(unique) {
+		return nil, errInvalidMedia
+	}
+	for _, m := range found {
+		if m.UserID != userId {
+			return nil, errInvalidMedia
+		}
+	}
+	return unique, nil
+}
+
+// DeleteUnattachedMedia removes uploads that no chirp has used within
+// unattachedMediaRetention, along with their files.
+func (cfg *ApiConfig) DeleteUnattachedMedia(ctx context.Context) error {
+	for {
+		keys, err := cfg.Db.DeleteUnattachedMedia(ctx, database.DeleteUnattachedMediaParams{
+			CreatedBefore: time.Now().Add(-unattachedMediaRetention),
+			Limit:         unattachedMediaBatchSize,
+		})
+		if err != nil {
+			return err
+		}
+		cfg.deleteBlobs(ctx, keys)
+		if len(keys) < unattachedMediaBatchSize {
+			return nil
+		}
+	}
+}
+
+// deleteBlobs removes the files of media rows that are already gone. There
+// is nothing left to retry from, so failures are only logged.
+func (cfg *ApiConfig) deleteBlobs(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		if err := cfg.Blobs.Delete(ctx, key); err != nil {
+			log.Printf("Error deleting media '%s': %s", key, err)
+		}
+	}
+}
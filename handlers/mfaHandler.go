@@ -0,0 +1,333 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/JosueAD95/Server-course/internal/auth"
+	database "github.com/JosueAD95/Server-course/internal/database"
+)
+
+const (
+	totpIssuer                   = "Chirpy"
+	mfaTokenLifetime             = 5 * time.Minute
+	recoveryCodeCount            = 10
+	securityEventMFAEnabled      = "mfa_enabled"
+	securityEventRecoveryCodeUse = "recovery_code_used"
+)
+
+func (cfg *ApiConfig) respondWithMFAChallenge(w http.ResponseWriter, userId uuid.UUID) {
+	type response struct {
+		MFARequired bool   `json:"mfa_required"`
+		MFAToken    string `json:"mfa_token"`
+	}
+
+	token, err := auth.MakeMFAToken(userId, cfg.JWTKeys, mfaTokenLifetime)
+	if err != nil {
+		log.Printf("Couldn't create MFA token: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, response{MFARequired: true, MFAToken: token})
+}
+
+// StartTOTPEnrolment gives the user a new TOTP secret. It isn't asked for
+// at login until ConfirmTOTPEnrolment sees a code made from it. The password
+// is asked for again, so a stolen session can't enrol its own authenticator.
+func (cfg *ApiConfig) StartTOTPEnrolment(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	type parameters struct {
+		Password string `json:"password"`
+	}
+	type response struct {
+		Secret     string `json:"secret"`
+		OtpauthURI string `json:"otpauth_uri"`
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		log.Printf("Error decoding JSON: %s", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userId := requestPrincipal(r).UserID
+	user, err := cfg.Db.GetUserById(r.Context(), userId)
+	if err != nil {
+		log.Printf("Error retriaving user '%s': %s", userId.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if !cfg.confirmPassword(w, r, user, params.Password, "Password is incorrect") {
+		return
+	}
+
+	secret := auth.MakeTOTPSecret()
+	started, err := cfg.Db.StartTOTPEnrolment(r.Context(), database.StartTOTPEnrolmentParams{
+		UserID: userId,
+		Secret: secret,
+	})
+	if err != nil {
+		log.Printf("Error starting TOTP enrolment of user '%s': %s", userId.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if started == 0 {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, response{
+		Secret:     secret,
+		OtpauthURI: auth.TOTPURI(secret, user.Email, totpIssuer),
+	})
+}
+
+// ConfirmTOTPEnrolment turns two-factor authentication on once the user
+// shows a valid code, and hands out the recovery codes. They are only ever
+// returned here.
+func (cfg *ApiConfig) ConfirmTOTPEnrolment(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	type parameters struct {
+		Code string `json:"code"`
+	}
+	type response struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		log.Printf("Error decoding JSON: %s", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	userId := requestPrincipal(r).UserID
+
+	tx, err := cfg.DbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Error starting transaction: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.Db.WithTx(tx)
+
+	totp, err := qtx.GetUserTOTP(r.Context(), userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "No two-factor enrolment in progress")
+		return
+	}
+	if err != nil {
+		log.Printf("Error retriaving TOTP of user '%s': %s", userId.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if totp.ConfirmedAt.Valid {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled")
+		return
+	}
+
+	step, err := auth.ValidateTOTP(totp.Secret, params.Code, time.Now(), totp.LastUsedStep)
+	if err != nil {
+		log.Printf("Error confirming TOTP of user '%s': %s", userId.String(), err)
+		respondWithError(w, http.StatusBadRequest, "Invalid code")
+		return
+	}
+
+	confirmed, err := qtx.ConfirmTOTP(r.Context(), database.ConfirmTOTPParams{
+		UserID:       userId,
+		LastUsedStep: step,
+	})
+	if err == nil && confirmed == 0 {
+		err = errors.New("enrolment changed while confirming")
+	}
+	if err != nil {
+		log.Printf("Error confirming TOTP of user '%s': %s", userId.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	codes := auth.MakeRecoveryCodes(recoveryCodeCount)
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = auth.HashRecoveryCode(code)
+	}
+	err = qtx.DeleteRecoveryCodes(r.Context(), userId)
+	if err == nil {
+		err = qtx.AddRecoveryCodes(r.Context(), database.AddRecoveryCodesParams{
+			UserID:     userId,
+			CodeHashes: hashes,
+		})
+	}
+	if err != nil {
+		log.Printf("Error saving recovery codes of user '%s': %s", userId.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = qtx.CreateSecurityEvent(r.Context(), database.CreateSecurityEventParams{
+		UserID:    userId,
+		EventType: securityEventMFAEnabled,
+		Details:   "two-factor authentication enabled from " + clientIP(r),
+	})
+	if err != nil {
+		log.Printf("Error recording security event: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing TOTP enrolment: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response{RecoveryCodes: codes})
+}
+
+// CompleteMFALogin exchanges the MFA token from Login and either a TOTP
+// code or a recovery code for a session. Wrong codes count as failed logins.
+func (cfg *ApiConfig) CompleteMFALogin(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	type parameters struct {
+		MFAToken     string `json:"mfa_token"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		log.Printf("Error decoding JSON: %s", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if (params.Code == "") == (params.RecoveryCode == "") {
+		respondWithError(w, http.StatusBadRequest, "Exactly one of code and recovery_code is required")
+		return
+	}
+
+	mfaToken, err := auth.ValidateMFAToken(params.MFAToken, cfg.JWTKeys)
+	if err != nil {
+		log.Printf("Invalid MFA token: %s", err)
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired MFA token")
+		return
+	}
+
+	userId := mfaToken.UserID
+	u, err := cfg.Db.GetUserById(r.Context(), userId)
+	if err != nil {
+		log.Printf("Error retriaving user '%s': %s", userId.String(), err)
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired MFA token")
+		return
+	}
+
+	retryAfter, err := cfg.loginRetryAfter(r, u.Email)
+	if err != nil {
+		log.Printf("Error checking failed logins of user (%s): %s", u.Email, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if retryAfter > 0 {
+		log.Printf("Throttled MFA login of user (%s) from %s", u.Email, clientIP(r))
+		respondWithLoginThrottled(w, retryAfter)
+		return
+	}
+
+	// The token is claimed before the code is looked at, so a replayed
+	// token can't spend a recovery code. A wrong code gives it back, and
+	// the user can retry with the same token.
+	claimed, err := cfg.Db.UseMFAToken(r.Context(), database.UseMFATokenParams{
+		TokenID:   mfaToken.ID,
+		ExpiresAt: mfaToken.ExpiresAt,
+	})
+	if err != nil {
+		log.Printf("Error using MFA token of user (%s): %s", u.Email, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if claimed == 0 {
+		log.Printf("Replayed MFA token of user (%s)", u.Email)
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired MFA token")
+		return
+	}
+
+	if params.Code != "" {
+		err = cfg.useTOTPCode(r, userId, params.Code)
+	} else {
+		err = cfg.useRecoveryCode(r, userId, params.RecoveryCode)
+	}
+	if err != nil {
+		log.Printf("Failed MFA login of user (%s): %s", u.Email, err)
+		if err := cfg.Db.ReleaseMFAToken(r.Context(), mfaToken.ID); err != nil {
+			log.Printf("Error releasing MFA token of user (%s): %s", u.Email, err)
+		}
+		if err := cfg.recordLoginFailure(r, u.Email, uuid.NullUUID{UUID: userId, Valid: true}); err != nil {
+			log.Printf("Error recording failed login of user (%s): %s", u.Email, err)
+		}
+		respondWithError(w, http.StatusUnauthorized, "Invalid code")
+		return
+	}
+
+	if _, err := cfg.Db.ClearLoginFailures(r.Context(), loginEmailKey(u.Email)); err != nil {
+		log.Printf("Error clearing failed logins of user (%s): %s", u.Email, err)
+	}
+	cfg.startSession(w, r, u)
+}
+
+// useTOTPCode accepts code once. Marking its time step used only succeeds
+// if no later code got in first, so concurrent replays lose too.
+func (cfg *ApiConfig) useTOTPCode(r *http.Request, userId uuid.UUID, code string) error {
+	totp, err := cfg.Db.GetUserTOTP(r.Context(), userId)
+	if err != nil {
+		return err
+	}
+	if !totp.ConfirmedAt.Valid {
+		return errors.New("two-factor authentication is not enabled")
+	}
+
+	step, err := auth.ValidateTOTP(totp.Secret, code, time.Now(), totp.LastUsedStep)
+	if err != nil {
+		return err
+	}
+	used, err := cfg.Db.UseTOTPStep(r.Context(), database.UseTOTPStepParams{
+		UserID:       userId,
+		LastUsedStep: step,
+	})
+	if err != nil {
+		return err
+	}
+	if used == 0 {
+		return auth.ErrTOTPCodeReused
+	}
+	return nil
+}
+
+func (cfg *ApiConfig) useRecoveryCode(r *http.Request, userId uuid.UUID, code string) error {
+	used, err := cfg.Db.UseRecoveryCode(r.Context(), database.UseRecoveryCodeParams{
+		UserID:   userId,
+		CodeHash: auth.HashRecoveryCode(code),
+	})
+	if err != nil {
+		return err
+	}
+	if used == 0 {
+		return errors.New("unknown or used recovery code")
+	}
+
+	return cfg.Db.CreateSecurityEvent(r.Context(), database.CreateSecurityEventParams{
+		UserID:    userId,
+		EventType: securityEventRecoveryCodeUse,
+		Details:   "signed in with a recovery code from " + clientIP(r),
+	})
+}
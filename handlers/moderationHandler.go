@@ -0,0 +1,197 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	database "github.com/JosueAD95/Server-course/internal/database"
+	"github.com/JosueAD95/Server-course/internal/moderation"
+	model "github.com/JosueAD95/Server-course/models"
+)
+
+type bannedWordRequest struct {
+	Word   string `json:"word"`
+	Action string `json:"action"`
+}
+
+// validate normalizes the word the same way chirp bodies are matched and
+// defaults the action to mask. Errors are meant for the client.
+func (b *bannedWordRequest) validate() error {
+	word, err := moderation.ValidateWord(b.Word)
+	if err != nil {
+		return err
+	}
+	b.Word = word
+
+	if b.Action == "" {
+		b.Action = string(moderation.ActionMask)
+	}
+	if !moderation.Action(b.Action).Valid() {
+		return errors.New("action must be one of mask, reject or flag")
+	}
+	return nil
+}
+
+func (cfg *ApiConfig) ListBannedWords(w http.ResponseWriter, r *http.Request) {
+	dbWords, err := cfg.Db.ListBannedWords(r.Context())
+	if err != nil {
+		log.Printf("Error retriaving banned words: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	words := make([]model.BannedWord, len(dbWords))
+	for i, word := range dbWords {
+		words[i].MapDBBannedWord(word)
+	}
+	respondWithJSON(w, http.StatusOK, words)
+}
+
+func (cfg *ApiConfig) CreateBannedWord(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	params := bannedWordRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		log.Printf("Error decoding JSON: %s", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := params.validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	dbWord, err := cfg.Db.CreateBannedWord(r.Context(), database.CreateBannedWordParams{
+		Word:   params.Word,
+		Action: params.Action,
+	})
+	if isUniqueViolation(err) {
+		respondWithError(w, http.StatusConflict, "Word is already banned")
+		return
+	}
+	if err != nil {
+		log.Printf("Error creating banned word '%s': %s", params.Word, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	word := model.BannedWord{}
+	word.MapDBBannedWord(dbWord)
+	respondWithJSON(w, http.StatusCreated, word)
+}
+
+func (cfg *ApiConfig) UpdateBannedWord(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	wordID, err := uuid.Parse(r.PathValue("wordID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		log.Printf("Error parsing wordID parameter: %s", err)
+		return
+	}
+
+	params := bannedWordRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		log.Printf("Error decoding JSON: %s", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := params.validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if _, err := cfg.Db.GetBannedWord(r.Context(), wordID); err != nil {
+		log.Printf("Error retriaving banned word '%s': %s", wordID.String(), err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	dbWord, err := cfg.Db.UpdateBannedWord(r.Context(), database.UpdateBannedWordParams{
+		ID:     wordID,
+		Word:   params.Word,
+		Action: params.Action,
+	})
+	if isUniqueViolation(err) {
+		respondWithError(w, http.StatusConflict, "Word is already banned")
+		return
+	}
+	if err != nil {
+		log.Printf("Error updating banned word '%s': %s", wordID.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	word := model.BannedWord{}
+	word.MapDBBannedWord(dbWord)
+	respondWithJSON(w, http.StatusOK, word)
+}
+
+func (cfg *ApiConfig) DeleteBannedWord(w http.ResponseWriter, r *http.Request) {
+	wordID, err := uuid.Parse(r.PathValue("wordID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		log.Printf("Error parsing wordID parameter: %s", err)
+		return
+	}
+
+	rows, err := cfg.Db.DeleteBannedWord(r.Context(), wordID)
+	if err != nil {
+		log.Printf("Error deleting banned word '%s': %s", wordID.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if rows == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *ApiConfig) ListFlaggedChirps(w http.ResponseWriter, r *http.Request) {
+	dbFlags, err := cfg.Db.ListPendingChirpFlags(r.Context())
+	if err != nil {
+		log.Printf("Error retriaving flagged chirps: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	flags := make([]model.ChirpFlag, len(dbFlags))
+	for i, flag := range dbFlags {
+		flags[i].MapDBChirpFlag(flag)
+	}
+	respondWithJSON(w, http.StatusOK, flags)
+}
+
+func (cfg *ApiConfig) ResolveChirpFlag(w http.ResponseWriter, r *http.Request) {
+	flagID, err := uuid.Parse(r.PathValue("flagID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		log.Printf("Error parsing flagID parameter: %s", err)
+		return
+	}
+
+	rows, err := cfg.Db.ResolveChirpFlag(r.Context(), flagID)
+	if err != nil {
+		log.Printf("Error resolving chirp flag '%s': %s", flagID.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if rows == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func isUniqueViolation(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == "23505"
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+
+	util "github.com/JosueAD95/Server-course/utils"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+type pageParams struct {
+	Limit  int32
+	Cursor *util.Cursor
+}
+
+func parsePageParams(r *http.Request) (pageParams, error) {
+	page := pageParams{Limit: defaultPageSize}
+
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > maxPageSize {
+			return page, errors.New("limit must be between 1 and " + strconv.Itoa(maxPageSize))
+		}
+		page.Limit = int32(n)
+	}
+
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		c, err := util.DecodeCursor(cursor)
+		if err != nil {
+			return page, err
+		}
+		page.Cursor = &c
+	}
+	return page, nil
+}
+
+// fetchLimit asks the database for one extra row so we know whether a next
+// page exists without a separate COUNT query.
+func (p pageParams) fetchLimit() int32 {
+	return p.Limit + 1
+}
+
+func (p pageParams) cursorCreatedAt() sql.NullTime {
+	if p.Cursor == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: p.Cursor.CreatedAt, Valid: true}
+}
+
+func (p pageParams) cursorID() uuid.NullUUID {
+	if p.Cursor == nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: p.Cursor.ID, Valid: true}
+}
+
+func (p pageParams) cursorRank() sql.NullFloat64 {
+	if p.Cursor == nil || p.Cursor.Rank == nil {
+		return sql.NullFloat64{}
+	}
+	return sql.NullFloat64{Float64: float64(*p.Cursor.Rank), Valid: true}
+}
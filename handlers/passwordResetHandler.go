@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/JosueAD95/Server-course/internal/auth"
+	database "github.com/JosueAD95/Server-course/internal/database"
+	"github.com/JosueAD95/Server-course/internal/mailer"
+)
+
+const (
+	passwordResetTokenLifetime = time.Hour
+	securityEventPasswordReset = "password_reset"
+)
+
+// RequestPasswordReset mails a single-use reset link to the account with the
+// given email. The answer is the same whether or not there is one, and the
+// mail goes out after responding so timing doesn't give it away either.
+// Requests are throttled per email and per client like logins, but on keys
+// of their own, so asking for resets can't lock anyone out of logging in.
+func (cfg *ApiConfig) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	type parameters struct {
+		Email string `json:"email"`
+	}
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		log.Printf("Error decoding JSON: %s", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	emailKey := resetEmailKey(params.Email)
+	ipKey := resetIPKey(clientIP(r))
+	blockedUntil, err := cfg.Db.GetLoginBlockedUntil(r.Context(), []string{emailKey, ipKey})
+	if err != nil {
+		log.Printf("Error checking password reset requests for (%s): %s", params.Email, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if blockedUntil.Valid && time.Until(blockedUntil.Time) > 0 {
+		log.Printf("Throttled password reset for (%s) from %s", params.Email, clientIP(r))
+		respondWithThrottled(w, time.Until(blockedUntil.Time), "Too many password reset requests, try again later")
+		return
+	}
+	if _, err := cfg.countLoginFailure(r.Context(), ipKey, cfg.IPLoginThrottle); err != nil {
+		log.Printf("Error counting password reset request from %s: %s", clientIP(r), err)
+	}
+	if _, err := cfg.countLoginFailure(r.Context(), emailKey, cfg.LoginThrottle); err != nil {
+		log.Printf("Error counting password reset request for (%s): %s", params.Email, err)
+	}
+
+	queued := cfg.MailQueue.Enqueue(func(ctx context.Context) {
+		if err := cfg.sendPasswordReset(ctx, params.Email); err != nil {
+			log.Printf("Error sending password reset to (%s): %s", params.Email, err)
+		}
+	})
+	if !queued {
+		log.Printf("Dropped password reset to (%s): mail queue is full", params.Email)
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (cfg *ApiConfig) sendPasswordReset(ctx context.Context, email string) error {
+	user, err := cfg.Db.GetUserByEmail(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token := auth.MakeRefreshToken()
+	err = cfg.Db.CreatePasswordResetToken(ctx, database.CreatePasswordResetTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(passwordResetTokenLifetime),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := cfg.BaseURL + "/app/reset-password/?token=" + url.QueryEscape(token)
+	return cfg.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your Chirpy password",
+		Body: fmt.Sprintf(`Someone asked to reset the password of your Chirpy account.
+
+To choose a new password, open this link within an hour:
+
+%s
+
+If it wasn't you, ignore this email and your password stays as it is.
+`, link),
+	})
+}
+
+// ConfirmPasswordReset sets a new password with a token from
+// RequestPasswordReset. Every session and personal access token of the user
+// is ended, and any other reset links they were sent stop working.
+func (cfg *ApiConfig) ConfirmPasswordReset(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	type parameters struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		log.Printf("Error decoding JSON: %s", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := cfg.PasswordPolicy.Validate(params.Password); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	hashedPassword, err := auth.HashPassword(params.Password)
+	if err != nil {
+		log.Printf("Error hashing the password: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := cfg.DbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Error starting transaction: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.Db.WithTx(tx)
+
+	userId, err := qtx.UsePasswordResetToken(r.Context(), auth.HashToken(params.Token))
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusBadRequest, "Invalid or expired reset token")
+		return
+	}
+	if err != nil {
+		log.Printf("Error using password reset token: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = qtx.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{
+		ID:             userId,
+		HashedPassword: hashedPassword,
+	})
+	if err == nil {
+		err = qtx.InvalidatePasswordResetTokens(r.Context(), userId)
+	}
+	if err == nil {
+		_, err = qtx.RevokeAllUserSessions(r.Context(), userId)
+	}
+	if err == nil {
+		err = qtx.RevokeAllPersonalAccessTokens(r.Context(), userId)
+	}
+	if err == nil {
+		err = qtx.BumpUserTokenVersion(r.Context(), userId)
+	}
+	if err != nil {
+		log.Printf("Error resetting password of user '%s': %s", userId.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = qtx.CreateSecurityEvent(r.Context(), database.CreateSecurityEventParams{
+		UserID:    userId,
+		EventType: securityEventPasswordReset,
+		Details:   "password reset from " + clientIP(r),
+	})
+	if err != nil {
+		log.Printf("Error recording security event: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing password reset: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/JosueAD95/Server-course/internal/auth"
+	database "github.com/JosueAD95/Server-course/internal/database"
+	model "github.com/JosueAD95/Server-course/models"
+)
+
+// setUserRole changes the role of userId and records who did it. changedBy
+// is null for changes made outside the API. Setting the role a user already
+// has is not recorded.
+func (cfg *ApiConfig) setUserRole(ctx context.Context, userId uuid.UUID, changedBy uuid.NullUUID, role auth.Role) error {
+	tx, err := cfg.DbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := cfg.Db.WithTx(tx)
+
+	oldRole, err := qtx.GetUserRoleForUpdate(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if oldRole == string(role) {
+		return nil
+	}
+
+	if err := qtx.SetUserRole(ctx, database.SetUserRoleParams{ID: userId, Role: string(role)}); err != nil {
+		return err
+	}
+	if _, err := qtx.CreateRoleChange(ctx, database.CreateRoleChangeParams{
+		UserID:    userId,
+		ChangedBy: changedBy,
+		OldRole:   oldRole,
+		NewRole:   string(role),
+	}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// BootstrapAdmin makes the user with email an admin, so a fresh deployment
+// has someone who can grant roles through the API.
+func (cfg *ApiConfig) BootstrapAdmin(ctx context.Context, email string) error {
+	user, err := cfg.Db.GetUserByEmail(ctx, email)
+	if err != nil {
+		return fmt.Errorf("finding admin user '%s': %w", email, err)
+	}
+	return cfg.setUserRole(ctx, user.ID, uuid.NullUUID{}, auth.RoleAdmin)
+}
+
+func (cfg *ApiConfig) GrantUserRole(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	type parameters struct {
+		Role string `json:"role"`
+	}
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		log.Printf("Error decoding JSON: %s", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	role, err := auth.ParseRole(params.Role)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "role must be one of user, moderator or admin")
+		return
+	}
+
+	cfg.changeUserRole(w, r, role)
+}
+
+// RevokeUserRole puts the user back to the plain user role.
+func (cfg *ApiConfig) RevokeUserRole(w http.ResponseWriter, r *http.Request) {
+	cfg.changeUserRole(w, r, auth.RoleUser)
+}
+
+func (cfg *ApiConfig) changeUserRole(w http.ResponseWriter, r *http.Request, role auth.Role) {
+	userId, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		log.Printf("Error parsing userID parameter: %s", err)
+		return
+	}
+
+	principal := requestPrincipal(r)
+	// Admins can't demote themselves, so there is always one left.
+	if userId == principal.UserID {
+		respondWithError(w, http.StatusForbidden, "You can't change your own role")
+		return
+	}
+
+	err = cfg.setUserRole(r.Context(), userId, uuid.NullUUID{UUID: principal.UserID, Valid: true}, role)
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Printf("User '%s' not found", userId.String())
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error setting role of user '%s' to '%s': %s", userId.String(), role, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	dbUser, err := cfg.Db.GetUserById(r.Context(), userId)
+	if err != nil {
+		log.Printf("Error retriaving user '%s': %s", userId.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	user := model.User{}
+	user.MapDbUser(dbUser)
+	respondWithJSON(w, http.StatusOK, user)
+}
+
+// ListRoleChanges returns the audit trail of role changes, newest first,
+// optionally narrowed to one user with ?user_id=.
+func (cfg *ApiConfig) ListRoleChanges(w http.ResponseWriter, r *http.Request) {
+	userId := uuid.NullUUID{}
+	if s := r.URL.Query().Get("user_id"); s != "" {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid user_id")
+			return
+		}
+		userId = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	dbChanges, err := cfg.Db.ListRoleChanges(r.Context(), userId)
+	if err != nil {
+		log.Printf("Error retriaving role changes: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	changes := make([]model.RoleChange, len(dbChanges))
+	for i, change := range dbChanges {
+		changes[i].MapDBRoleChange(change)
+	}
+	respondWithJSON(w, http.StatusOK, changes)
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	database "github.com/JosueAD95/Server-course/internal/database"
+	model "github.com/JosueAD95/Server-course/models"
+	util "github.com/JosueAD95/Server-course/utils"
+)
+
+const maxSearchQueryLength = 256
+
+// SearchChirps runs a full-text search over chirp bodies. The q parameter
+// accepts web search syntax: "quoted phrases", OR and -excluded words.
+func (cfg *ApiConfig) SearchChirps(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" || len(query) > maxSearchQueryLength {
+		respondWithError(w, http.StatusBadRequest, "q must be between 1 and 256 characters")
+		return
+	}
+
+	page, err := parsePageParams(r)
+	if err != nil || (page.Cursor != nil && page.Cursor.Rank == nil) {
+		log.Printf("Error parsing pagination parameters: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	params := database.SearchChirpsParams{
+		Query:           query,
+		CursorRank:      page.cursorRank(),
+		CursorCreatedAt: page.cursorCreatedAt(),
+		CursorID:        page.cursorID(),
+		Limit:           page.fetchLimit(),
+	}
+
+	if authorId := r.URL.Query().Get("author_id"); authorId != "" {
+		id, err := uuid.Parse(authorId)
+		if err != nil {
+			log.Printf("Error parsing author_id parameter: %s", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		params.AuthorID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	if params.Since, err = parseTimeParam(r, "since"); err != nil {
+		respondWithError(w, http.StatusBadRequest, "since must be an RFC 3339 timestamp")
+		return
+	}
+	if params.Until, err = parseTimeParam(r, "until"); err != nil {
+		respondWithError(w, http.StatusBadRequest, "until must be an RFC 3339 timestamp")
+		return
+	}
+
+	rows, err := cfg.Db.SearchChirps(r.Context(), params)
+	if err != nil {
+		log.Printf("Error searching chirps for '%s': %s", query, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	chirpPage := model.ChirpPage{}
+	if len(rows) > int(page.Limit) {
+		rows = rows[:page.Limit]
+		last := rows[len(rows)-1]
+		chirpPage.NextCursor = util.EncodeCursor(util.Cursor{CreatedAt: last.CreatedAt, ID: last.ID, Rank: &last.Rank})
+	}
+	chirpPage.Chirps = make([]model.Chirp, len(rows))
+	for i, row := range rows {
+		chirpPage.Chirps[i].MapDBChirp(database.Chirp{
+			ID:        row.ID,
+			CreatedAt: row.CreatedAt,
+			UpdatedAt: row.UpdatedAt,
+			Body:      row.Body,
+			UserID:    row.UserID,
+			InReplyTo: row.InReplyTo,
+			DeletedAt: row.DeletedAt,
+		})
+	}
+
+	if err := cfg.decorateChirps(r.Context(), chirpPage.Chirps, viewerID(r)); err != nil {
+		log.Printf("Error loading chirp details: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, chirpPage)
+}
+
+func parseTimeParam(r *http.Request, name string) (sql.NullTime, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return sql.NullTime{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return sql.NullTime{}, err
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}, nil
+}
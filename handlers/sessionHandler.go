@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"log"
+	"net"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	database "github.com/JosueAD95/Server-course/internal/database"
+	model "github.com/JosueAD95/Server-course/models"
+)
+
+const (
+	maxUserAgentLength              = 512
+	securityEventAllSessionsRevoked = "all_sessions_revoked"
+)
+
+// clientIP is the address of the connecting peer. Proxy headers are
+// ignored because anyone can set them.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func userAgent(r *http.Request) string {
+	ua := r.UserAgent()
+	if len(ua) > maxUserAgentLength {
+		ua = ua[:maxUserAgentLength]
+	}
+	return ua
+}
+
+func (cfg *ApiConfig) ListSessions(w http.ResponseWriter, r *http.Request) {
+	userId := requestPrincipal(r).UserID
+
+	dbSessions, err := cfg.Db.ListUserSessions(r.Context(), userId)
+	if err != nil {
+		log.Printf("Error retriaving sessions of user '%s': %s", userId.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	sessions := make([]model.Session, len(dbSessions))
+	for i, dbSession := range dbSessions {
+		sessions[i].MapDBSession(dbSession)
+	}
+	respondWithJSON(w, http.StatusOK, sessions)
+}
+
+// RevokeSession logs one session out. Access JWTs already issued to it
+// stay valid until they expire; use RevokeAllSessions to cut those too.
+func (cfg *ApiConfig) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	sessionId, err := uuid.Parse(r.PathValue("sessionID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		log.Printf("Error parsing sessionID parameter: %s", err)
+		return
+	}
+
+	userId := requestPrincipal(r).UserID
+
+	revoked, err := cfg.Db.RevokeUserSession(r.Context(), database.RevokeUserSessionParams{
+		FamilyID: sessionId,
+		UserID:   userId,
+	})
+	if err != nil {
+		log.Printf("Error revoking session '%s': %s", sessionId.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if revoked == 0 {
+		log.Printf("Session '%s' of user '%s' not found", sessionId.String(), userId.String())
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeAllSessions revokes every refresh token of the caller and bumps
+// their token version so outstanding access JWTs stop working as well.
+func (cfg *ApiConfig) RevokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	userId := requestPrincipal(r).UserID
+
+	tx, err := cfg.DbConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Error starting transaction: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.Db.WithTx(tx)
+
+	if _, err := qtx.RevokeAllUserSessions(r.Context(), userId); err != nil {
+		log.Printf("Error revoking sessions of user '%s': %s", userId.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := qtx.BumpUserTokenVersion(r.Context(), userId); err != nil {
+		log.Printf("Error bumping token version of user '%s': %s", userId.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = qtx.CreateSecurityEvent(r.Context(), database.CreateSecurityEventParams{
+		UserID:    userId,
+		EventType: securityEventAllSessionsRevoked,
+		Details:   "logged out of all sessions from " + clientIP(r),
+	})
+	if err != nil {
+		log.Printf("Error recording security event: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing session revocation: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
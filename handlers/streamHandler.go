@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/JosueAD95/Server-course/internal/auth"
+	"github.com/JosueAD95/Server-course/internal/stream"
+)
+
+const streamHeartbeat = 15 * time.Second
+
+type chirpDeletedEvent struct {
+	Id     uuid.UUID `json:"id"`
+	UserId uuid.UUID `json:"user_id"`
+}
+
+// publishChirpEvent is called after the change is committed. A failure only
+// costs live subscribers an event, so it is logged and not reported to the
+// client.
+func (cfg *ApiConfig) publishChirpEvent(ctx context.Context, eventType stream.EventType, chirpId, authorId uuid.UUID, payload any) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Error marshalling stream event: %s", err)
+		return
+	}
+	err = cfg.Stream.Publish(ctx, stream.Event{
+		Type:     eventType,
+		ChirpID:  chirpId,
+		AuthorID: authorId,
+		Data:     data,
+	})
+	if err != nil {
+		log.Printf("Error publishing %s event for chirp '%s': %s", eventType, chirpId.String(), err)
+	}
+}
+
+func (cfg *ApiConfig) StreamChirps(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		log.Printf("Response writer does not support streaming")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// A nil set means every author is wanted.
+	var authors map[uuid.UUID]bool
+
+	if authorId := r.URL.Query().Get("author_id"); authorId != "" {
+		id, err := uuid.Parse(authorId)
+		if err != nil {
+			log.Printf("Error parsing author_id parameter: %s", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		authors = map[uuid.UUID]bool{id: true}
+	}
+
+	if r.URL.Query().Get("following") == "true" {
+		principal, ok := auth.PrincipalFromContext(r.Context())
+		if !ok {
+			respondWithAuthChallenge(w, auth.ScopeChirpsRead, errNoCredentials)
+			return
+		}
+		userId := principal.UserID
+
+		// The follow list is read once; follows made while connected
+		// take effect on the next reconnect.
+		followees, err := cfg.Db.GetFolloweeIds(r.Context(), userId)
+		if err != nil {
+			log.Printf("Error retriaving followees of user '%s': %s", userId.String(), err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		following := make(map[uuid.UUID]bool, len(followees))
+		for _, id := range followees {
+			if authors == nil || authors[id] {
+				following[id] = true
+			}
+		}
+		authors = following
+	}
+
+	// EventSource sends Last-Event-ID on reconnect. The query parameter is
+	// for clients that can't set headers.
+	lastEventId := r.Header.Get("Last-Event-ID")
+	if lastEventId == "" {
+		lastEventId = r.URL.Query().Get("last_event_id")
+	}
+
+	backlog, sub, resumed := cfg.Stream.Subscribe(lastEventId)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(e stream.Event) error {
+		if authors != nil && !authors[e.AuthorID] {
+			return nil
+		}
+		_, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", e.ID, e.Type, e.Data)
+		return err
+	}
+
+	if !resumed {
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: {}\n\n", stream.EventReset); err != nil {
+			return
+		}
+	}
+
+	for _, e := range backlog {
+		if err := send(e); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-sub.C:
+			// A closed channel means we fell behind; the client
+			// reconnects and resumes from the last ID it saw.
+			if !ok {
+				return
+			}
+			if err := send(e); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
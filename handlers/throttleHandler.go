@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/JosueAD95/Server-course/internal/auth"
+	database "github.com/JosueAD95/Server-course/internal/database"
+)
+
+const (
+	securityEventAccountLocked   = "account_locked"
+	securityEventAccountUnlocked = "account_unlocked"
+)
+
+func loginEmailKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func loginIPKey(ip string) string {
+	return "ip:" + ip
+}
+
+func resetEmailKey(email string) string {
+	return "reset:" + loginEmailKey(email)
+}
+
+func resetIPKey(ip string) string {
+	return "reset:" + loginIPKey(ip)
+}
+
+// loginRetryAfter returns how much longer a login for email from r has to
+// wait because of earlier failures, or zero if it may go ahead.
+func (cfg *ApiConfig) loginRetryAfter(r *http.Request, email string) (time.Duration, error) {
+	blockedUntil, err := cfg.Db.GetLoginBlockedUntil(r.Context(), []string{
+		loginEmailKey(email),
+		loginIPKey(clientIP(r)),
+	})
+	if err != nil || !blockedUntil.Valid {
+		return 0, err
+	}
+	return time.Until(blockedUntil.Time), nil
+}
+
+// recordLoginFailure counts a failed login against both the email and the
+// client address. userId is only valid when the email has an account; it is
+// told through a security event when it gets locked out.
+func (cfg *ApiConfig) recordLoginFailure(r *http.Request, email string, userId uuid.NullUUID) error {
+	failures, err := cfg.countLoginFailure(r.Context(), loginIPKey(clientIP(r)), cfg.IPLoginThrottle)
+	if err != nil {
+		return err
+	}
+	if cfg.IPLoginThrottle.Locks(int(failures)) {
+		log.Printf("Locked out logins from %s after %d failures", clientIP(r), failures)
+	}
+
+	failures, err = cfg.countLoginFailure(r.Context(), loginEmailKey(email), cfg.LoginThrottle)
+	if err != nil {
+		return err
+	}
+	if int(failures) == cfg.LoginThrottle.Threshold && userId.Valid {
+		return cfg.Db.CreateSecurityEvent(r.Context(), database.CreateSecurityEventParams{
+			UserID:    userId.UUID,
+			EventType: securityEventAccountLocked,
+			Details:   "locked out after repeated failed logins, the last from " + clientIP(r),
+		})
+	}
+	return nil
+}
+
+// countLoginFailure adds a failure to key and blocks it for as long as
+// throttle asks. A key that hasn't failed for a whole lockout period starts
+// counting from one again.
+func (cfg *ApiConfig) countLoginFailure(ctx context.Context, key string, throttle auth.LoginThrottle) (int32, error) {
+	now := time.Now()
+	failures, err := cfg.Db.RecordLoginFailure(ctx, database.RecordLoginFailureParams{
+		Key:         key,
+		ResetBefore: now.Add(-throttle.Lockout),
+	})
+	if err != nil {
+		return 0, err
+	}
+	err = cfg.Db.SetLoginBlockedUntil(ctx, database.SetLoginBlockedUntilParams{
+		Key:          key,
+		BlockedUntil: sql.NullTime{Time: now.Add(throttle.RetryAfter(int(failures))), Valid: true},
+	})
+	return failures, err
+}
+
+func respondWithLoginThrottled(w http.ResponseWriter, retryAfter time.Duration) {
+	respondWithThrottled(w, retryAfter, "Too many failed login attempts, try again later")
+}
+
+func respondWithThrottled(w http.ResponseWriter, retryAfter time.Duration, msg string) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	respondWithError(w, http.StatusTooManyRequests, msg)
+}
+
+// confirmPassword checks the password of a signed in user before a
+// sensitive change, and responds if it's wrong. Wrong passwords count as
+// failed logins, so they can't be guessed any faster here than at Login.
+func (cfg *ApiConfig) confirmPassword(w http.ResponseWriter, r *http.Request, user database.User, password, wrongMessage string) bool {
+	retryAfter, err := cfg.loginRetryAfter(r, user.Email)
+	if err != nil {
+		log.Printf("Error checking failed logins of user (%s): %s", user.Email, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return false
+	}
+	if retryAfter > 0 {
+		respondWithLoginThrottled(w, retryAfter)
+		return false
+	}
+	if err := auth.CheckPasswordHash(password, user.HashedPassword); err != nil {
+		log.Printf("Wrong password for user '%s': %s", user.ID.String(), err)
+		if err := cfg.recordLoginFailure(r, user.Email, uuid.NullUUID{UUID: user.ID, Valid: true}); err != nil {
+			log.Printf("Error recording failed login of user (%s): %s", user.Email, err)
+		}
+		respondWithError(w, http.StatusForbidden, wrongMessage)
+		return false
+	}
+	return true
+}
+
+// UnlockUser clears the failed logins counted against a user's email. Logins
+// from a locked out address stay blocked until their lockout ends.
+func (cfg *ApiConfig) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	userId, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		log.Printf("Error parsing userID parameter: %s", err)
+		return
+	}
+
+	user, err := cfg.Db.GetUserById(r.Context(), userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Printf("User '%s' not found", userId.String())
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error retriaving user '%s': %s", userId.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	cleared, err := cfg.Db.ClearLoginFailures(r.Context(), loginEmailKey(user.Email))
+	if err != nil {
+		log.Printf("Error clearing failed logins of user '%s': %s", userId.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if cleared > 0 {
+		err = cfg.Db.CreateSecurityEvent(r.Context(), database.CreateSecurityEventParams{
+			UserID:    userId,
+			EventType: securityEventAccountUnlocked,
+			Details:   "unlocked by " + requestPrincipal(r).UserID.String(),
+		})
+		if err != nil {
+			log.Printf("Error recording security event: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
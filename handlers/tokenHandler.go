@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/JosueAD95/Server-course/internal/auth"
+	database "github.com/JosueAD95/Server-course/internal/database"
+	model "github.com/JosueAD95/Server-course/models"
+)
+
+const (
+	maxTokenNameLength = 100
+	maxTokenLifetime   = 366 * 24 * time.Hour
+)
+
+// CreatePersonalAccessToken issues a token for scripts and bots. The token
+// is only returned here; we keep nothing but its digest.
+func (cfg *ApiConfig) CreatePersonalAccessToken(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	principal := requestPrincipal(r)
+
+	type parameters struct {
+		Name      string     `json:"name"`
+		Scopes    []string   `json:"scopes"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		log.Printf("Error decoding JSON: %s", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	params.Name = strings.TrimSpace(params.Name)
+	if params.Name == "" || len(params.Name) > maxTokenNameLength {
+		respondWithError(w, http.StatusBadRequest, "Token name is required and must be at most 100 characters")
+		return
+	}
+
+	scopes, err := auth.ValidateScopes(params.Scopes)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	expiresAt := sql.NullTime{}
+	if params.ExpiresAt != nil {
+		if !params.ExpiresAt.After(time.Now()) || params.ExpiresAt.After(time.Now().Add(maxTokenLifetime)) {
+			respondWithError(w, http.StatusBadRequest, "expires_at must be in the future and within a year")
+			return
+		}
+		expiresAt = sql.NullTime{Time: *params.ExpiresAt, Valid: true}
+	}
+
+	token := auth.MakePersonalAccessToken()
+	dbToken, err := cfg.Db.CreatePersonalAccessToken(r.Context(), database.CreatePersonalAccessTokenParams{
+		UserID:    principal.UserID,
+		Name:      params.Name,
+		TokenHash: auth.HashToken(token),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		log.Printf("Error creating personal access token for user '%s': %s", principal.UserID.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	resp := model.PersonalAccessToken{}
+	resp.MapDBPersonalAccessToken(dbToken)
+	resp.Token = token
+	respondWithJSON(w, http.StatusCreated, resp)
+}
+
+func (cfg *ApiConfig) ListPersonalAccessTokens(w http.ResponseWriter, r *http.Request) {
+	principal := requestPrincipal(r)
+
+	dbTokens, err := cfg.Db.ListPersonalAccessTokens(r.Context(), principal.UserID)
+	if err != nil {
+		log.Printf("Error retriaving personal access tokens of user '%s': %s", principal.UserID.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	tokens := make([]model.PersonalAccessToken, len(dbTokens))
+	for i, dbToken := range dbTokens {
+		tokens[i].MapDBPersonalAccessToken(dbToken)
+	}
+	respondWithJSON(w, http.StatusOK, tokens)
+}
+
+func (cfg *ApiConfig) RevokePersonalAccessToken(w http.ResponseWriter, r *http.Request) {
+	tokenId, err := uuid.Parse(r.PathValue("tokenID"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		log.Printf("Error parsing tokenID parameter: %s", err)
+		return
+	}
+
+	principal := requestPrincipal(r)
+
+	revoked, err := cfg.Db.RevokePersonalAccessToken(r.Context(), database.RevokePersonalAccessTokenParams{
+		ID:     tokenId,
+		UserID: principal.UserID,
+	})
+	if err != nil {
+		log.Printf("Error revoking personal access token '%s': %s", tokenId.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if revoked == 0 {
+		log.Printf("Personal access token '%s' of user '%s' not found", tokenId.String(), principal.UserID.String())
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -1,7 +1,10 @@
 package handler
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"time"
@@ -13,70 +16,186 @@ import (
 	model "github.com/JosueAD95/Server-course/models"
 )
 
-func (cfg ApiConfig) UpdateUserCredentials(w http.ResponseWriter, r *http.Request) {
+const (
+	securityEventRefreshTokenReuse = "refresh_token_reuse"
+	securityEventPasswordChanged   = "password_changed"
+)
+
+// UpdateUser changes the email, the password or both; fields left out stay
+// as they are. Either change needs the current password, so a stolen access
+// token can't be turned into the account. A new email only takes effect
+// once the link sent to it is followed. A new password ends every other
+// session and revokes their access tokens; the caller gets a fresh one.
+func (cfg *ApiConfig) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 
-	token, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		log.Printf("Couldn't find JWT: %s", err)
+	principal := requestPrincipal(r)
+	userId := principal.UserID
+
+	type parameters struct {
+		Email           *string `json:"email"`
+		Password        *string `json:"password"`
+		CurrentPassword string  `json:"current_password"`
+	}
+	type response struct {
+		model.User
+		Token string `json:"token,omitempty"`
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		log.Printf("Error decoding JSON: %s", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if params.Email == nil && params.Password == nil {
+		respondWithError(w, http.StatusBadRequest, "Nothing to update, send email or password")
 		return
 	}
 
-	userId, err := auth.ValidateJWT(token, cfg.JWTSecret)
+	email := ""
+	if params.Email != nil {
+		validated, err := auth.ValidateEmail(*params.Email)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		email = validated
+	}
+	if params.Password != nil {
+		if err := cfg.PasswordPolicy.Validate(*params.Password); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	current, err := cfg.Db.GetUserById(r.Context(), userId)
 	if err != nil {
-		w.WriteHeader(http.StatusUnauthorized)
-		log.Printf("Couldn't validate JWT: %s", err)
+		log.Printf("Error retriaving user '%s': %s", userId.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	type Request struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
+	if !cfg.confirmPassword(w, r, current, params.CurrentPassword, "Current password is incorrect") {
+		return
 	}
 
-	decoder := json.NewDecoder(r.Body)
-	user := Request{}
-	if err := decoder.Decode(&user); err != nil {
-		log.Printf("Error decoding body")
-		w.WriteHeader(http.StatusBadRequest)
-		return
+	// Asking for the current email again cancels a pending change.
+	pendingEmail := current.PendingEmail
+	if params.Email != nil {
+		pendingEmail = sql.NullString{}
+		if email != current.Email {
+			_, err := cfg.Db.GetUserByEmail(r.Context(), email)
+			if err == nil {
+				respondWithError(w, http.StatusConflict, "Email is already in use")
+				return
+			}
+			if !errors.Is(err, sql.ErrNoRows) {
+				log.Printf("Error searching for user (%s): %s", email, err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			pendingEmail = sql.NullString{String: email, Valid: true}
+		}
 	}
 
-	password, err := auth.HashPassword(user.Password)
+	hashedPassword := ""
+	if params.Password != nil {
+		hashedPassword, err = auth.HashPassword(*params.Password)
+		if err != nil {
+			log.Printf("Error hashing the password: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	tx, err := cfg.DbConn.BeginTx(r.Context(), nil)
 	if err != nil {
-		log.Printf("Error hashing the password: %s", err)
+		log.Printf("Error starting transaction: %s", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	defer tx.Rollback()
+	qtx := cfg.Db.WithTx(tx)
 
-	userParams := db.UpdateUserEmailAndPasswordParams{
-		ID:             userId,
-		Email:          user.Email,
-		HashedPassword: password,
+	if pendingEmail != current.PendingEmail {
+		err = qtx.SetUserPendingEmail(r.Context(), db.SetUserPendingEmailParams{
+			ID:           userId,
+			PendingEmail: pendingEmail,
+		})
+	}
+	if err == nil && params.Password != nil {
+		err = cfg.changePassword(r, qtx, principal, hashedPassword)
 	}
-
-	err = cfg.Db.UpdateUserEmailAndPassword(r.Context(), userParams)
 	if err != nil {
 		log.Printf("Error updating user '%s': %s", userId.String(), err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
-	user.Password = ""
-	data, err := json.Marshal(user)
+
+	dbUser, err := qtx.GetUserById(r.Context(), userId)
 	if err != nil {
-		log.Printf("Error marshalling User '%s': %s", userId.String(), err)
+		log.Printf("Error retriaving user '%s': %s", userId.String(), err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Add("Content-type", "application/json")
-	w.Write(data)
-	w.WriteHeader(http.StatusOK)
-	return
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing update of user '%s': %s", userId.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if params.Email != nil && pendingEmail.Valid {
+		cfg.sendEmailVerificationLater(userId, email)
+	}
+
+	resp := response{}
+	resp.User.MapDbUser(dbUser)
+	if params.Password != nil && !principal.IsPersonalAccessToken() {
+		resp.Token, err = auth.MakeJWT(userId, dbUser.TokenVersion, principal.SessionID, cfg.JWTKeys, time.Hour)
+		if err != nil {
+			log.Printf("Couldn't create access JWT: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+	respondWithJSON(w, http.StatusOK, resp)
 }
 
-func (cfg ApiConfig) AddUser(w http.ResponseWriter, r *http.Request) {
+// changePassword stores a new password hash and signs the user out
+// everywhere except the session making the change. Bumping the token
+// version also kills that session's access token, so the caller has to be
+// given a new one.
+func (cfg *ApiConfig) changePassword(r *http.Request, qtx *db.Queries, principal auth.Principal, hashedPassword string) error {
+	err := qtx.UpdateUserPassword(r.Context(), db.UpdateUserPasswordParams{
+		ID:             principal.UserID,
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = qtx.RevokeOtherUserSessions(r.Context(), db.RevokeOtherUserSessionsParams{
+		UserID:   principal.UserID,
+		FamilyID: principal.SessionID,
+	})
+	if err != nil {
+		return err
+	}
+	if err := qtx.BumpUserTokenVersion(r.Context(), principal.UserID); err != nil {
+		return err
+	}
+	if err := qtx.InvalidatePasswordResetTokens(r.Context(), principal.UserID); err != nil {
+		return err
+	}
+	return qtx.CreateSecurityEvent(r.Context(), db.CreateSecurityEventParams{
+		UserID:    principal.UserID,
+		EventType: securityEventPasswordChanged,
+		Details:   "password changed from " + clientIP(r),
+	})
+}
+
+func (cfg *ApiConfig) AddUser(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 	decoder := json.NewDecoder(r.Body)
 	newUser := model.User{}
@@ -87,8 +206,13 @@ func (cfg ApiConfig) AddUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if newUser.Email == "" {
-		w.WriteHeader(http.StatusBadRequest)
+	email, err := auth.ValidateEmail(newUser.Email)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := cfg.PasswordPolicy.Validate(newUser.Password); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 	password, err := auth.HashPassword(newUser.Password)
@@ -98,15 +222,20 @@ func (cfg ApiConfig) AddUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	userParams := db.CreateUserParams{
-		Email:          newUser.Email,
+		Email:          email,
 		HashedPassword: password,
 	}
 	rowUser, err := cfg.Db.CreateUser(r.Context(), userParams)
+	if isUniqueViolation(err) {
+		respondWithError(w, http.StatusConflict, "Email is already in use")
+		return
+	}
 	if err != nil {
 		log.Printf("Error creating user: %s", err)
 		w.WriteHeader(500)
 		return
 	}
+	cfg.sendEmailVerificationLater(rowUser.ID, rowUser.Email)
 
 	newUser.MapRowUser(rowUser)
 	data, err := json.Marshal(newUser)
@@ -119,7 +248,7 @@ func (cfg ApiConfig) AddUser(w http.ResponseWriter, r *http.Request) {
 	w.Write(data)
 }
 
-func (cfg ApiConfig) UpgradeUser(w http.ResponseWriter, r *http.Request) {
+func (cfg *ApiConfig) UpgradeUser(w http.ResponseWriter, r *http.Request) {
 	type event struct {
 		Data struct {
 			UserId uuid.UUID `json:"user_id"`
@@ -165,17 +294,15 @@ func (cfg ApiConfig) UpgradeUser(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func (cfg ApiConfig) Login(w http.ResponseWriter, r *http.Request) {
+// Login checks a user's password. Users without two-factor authentication
+// get a session straight away; the others get an MFA token to exchange for
+// one at CompleteMFALogin.
+func (cfg *ApiConfig) Login(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 	type parameters struct {
 		Password string `json:"password"`
 		Email    string `json:"email"`
 	}
-	type response struct {
-		model.User
-		Token        string `json:"token"`
-		RefreshToken string `json:"refresh_token"`
-	}
 
 	decoder := json.NewDecoder(r.Body)
 	params := parameters{}
@@ -186,24 +313,107 @@ func (cfg ApiConfig) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	u, err := cfg.Db.GetUserByEmail(r.Context(), params.Email)
-
+	retryAfter, err := cfg.loginRetryAfter(r, params.Email)
 	if err != nil {
+		log.Printf("Error checking failed logins of user (%s): %s", params.Email, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if retryAfter > 0 {
+		log.Printf("Throttled login of user (%s) from %s", params.Email, clientIP(r))
+		respondWithLoginThrottled(w, retryAfter)
+		return
+	}
+
+	// Unknown emails and wrong passwords take as long and get the same
+	// answer, so logins can't be used to find out who has an account.
+	u, err := cfg.Db.GetUserByEmail(r.Context(), params.Email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
 		log.Printf("Error searching for user (%s): %s", params.Email, err)
-		w.WriteHeader(http.StatusUnauthorized)
+		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
-	err = auth.CheckPasswordHash(params.Password, u.HashedPassword)
+	userId := uuid.NullUUID{}
+	if err == nil {
+		userId = uuid.NullUUID{UUID: u.ID, Valid: true}
+		err = auth.CheckPasswordHash(params.Password, u.HashedPassword)
+	} else {
+		auth.CheckDummyPassword(params.Password)
+	}
 	if err != nil {
-		log.Printf("Error comparing password of user (%s): %s", params.Email, err)
-		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Failed login of user (%s): %s", params.Email, err)
+		if err := cfg.recordLoginFailure(r, params.Email, userId); err != nil {
+			log.Printf("Error recording failed login of user (%s): %s", params.Email, err)
+		}
+		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password")
+		return
+	}
+
+	// Hashes from before Argon2id, or with older parameters, are upgraded
+	// while the plain password is at hand.
+	if auth.NeedsRehash(u.HashedPassword) {
+		cfg.rehashPassword(r, u.ID, params.Password)
+	}
+
+	// Failures keep counting until the second factor is in too, so the
+	// lockout also covers guessing codes.
+	totp, err := cfg.Db.GetUserTOTP(r.Context(), u.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Error retriaving TOTP of user (%s): %s", params.Email, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if err == nil && totp.ConfirmedAt.Valid {
+		cfg.respondWithMFAChallenge(w, u.ID)
 		return
 	}
 
+	if _, err := cfg.Db.ClearLoginFailures(r.Context(), loginEmailKey(params.Email)); err != nil {
+		log.Printf("Error clearing failed logins of user (%s): %s", params.Email, err)
+	}
+	cfg.startSession(w, r, u)
+}
+
+func (cfg *ApiConfig) rehashPassword(r *http.Request, userId uuid.UUID, password string) {
+	hashedPassword, err := auth.HashPassword(password)
+	if err == nil {
+		err = cfg.Db.UpdateUserPassword(r.Context(), db.UpdateUserPasswordParams{
+			ID:             userId,
+			HashedPassword: hashedPassword,
+		})
+	}
+	if err != nil {
+		log.Printf("Error rehashing password of user '%s': %s", userId.String(), err)
+	}
+}
+
+// startSession signs u in with a new access JWT and refresh token family.
+func (cfg *ApiConfig) startSession(w http.ResponseWriter, r *http.Request, u db.User) {
+	type response struct {
+		model.User
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	// Signing in during the grace period keeps the account.
+	if u.DeleteAfter.Valid {
+		if err := cfg.cancelAccountDeletion(r, u.ID); err != nil {
+			log.Printf("Error cancelling deletion of user '%s': %s", u.ID.String(), err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		u.DeleteAfter = sql.NullTime{}
+	}
+
+	refreshToken := auth.MakeRefreshToken()
 	refreshTokenParams := db.SaveRefreshTokenParams{
-		Token:     auth.MakeRefreshToken(),
-		UserID:    u.ID,
-		ExpiresAt: time.Now().AddDate(0, 0, 60),
+		TokenHash:  auth.HashToken(refreshToken),
+		UserID:     u.ID,
+		ExpiresAt:  time.Now().Add(cfg.RefreshTokenTTL),
+		FamilyID:   uuid.New(),
+		UserAgent:  userAgent(r),
+		IpAddress:  clientIP(r),
+		SignedInAt: time.Now(),
 	}
 
 	if _, err := cfg.Db.SaveRefreshToken(r.Context(), refreshTokenParams); err != nil {
@@ -214,7 +424,9 @@ func (cfg ApiConfig) Login(w http.ResponseWriter, r *http.Request) {
 
 	accessToken, err := auth.MakeJWT(
 		u.ID,
-		cfg.JWTSecret,
+		u.TokenVersion,
+		refreshTokenParams.FamilyID,
+		cfg.JWTKeys,
 		time.Hour,
 	)
 
@@ -225,27 +437,18 @@ func (cfg ApiConfig) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	resp := response{
-		User: model.User{
-			ID:          u.ID,
-			UpdatedAt:   u.UpdatedAt,
-			CreatedAt:   u.CreatedAt,
-			IsChirpyRed: u.IsChirpyRed,
-			Email:       u.Email,
-		},
 		Token:        accessToken,
-		RefreshToken: refreshTokenParams.Token,
+		RefreshToken: refreshToken,
 	}
-	data, err := json.Marshal(resp)
-	if err != nil {
-		log.Printf("Error marshalling JSON: %s", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-	w.WriteHeader(http.StatusOK)
-	w.Write(data)
+	resp.User.MapDbUser(u)
+	respondWithJSON(w, http.StatusOK, resp)
 }
 
-func (cfg ApiConfig) RefreshToken(w http.ResponseWriter, r *http.Request) {
+// RefreshToken exchanges a refresh token for a new access JWT and a new
+// refresh token in the same family. The old token stops working, so seeing
+// it again means it was copied: the whole family is revoked and the replay
+// is recorded as a security event.
+func (cfg *ApiConfig) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	token, err := auth.GetBearerToken(r.Header)
 	if err != nil {
 		log.Printf("Refesh token not found: %s", err)
@@ -253,22 +456,55 @@ func (cfg ApiConfig) RefreshToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userRow, err := cfg.Db.GetUserIdFromRefreshToken(r.Context(), token)
+	tx, err := cfg.DbConn.BeginTx(r.Context(), nil)
 	if err != nil {
-		log.Printf("Error searching refresh token '%s': %s", token, err)
-		w.WriteHeader(http.StatusUnauthorized)
+		log.Printf("Error starting transaction: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	defer tx.Rollback()
+	qtx := cfg.Db.WithTx(tx)
 
-	if userRow.RevokedAt.Valid {
-		log.Printf("The token was revoked at: %s", userRow.RevokedAt.Time)
-		w.WriteHeader(http.StatusUnauthorized)
+	tokenHash := auth.HashToken(token)
+	rotated, err := qtx.RotateRefreshToken(r.Context(), tokenHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		cfg.rejectRefreshToken(w, r, qtx, tx, tokenHash)
+		return
+	}
+	if err != nil {
+		log.Printf("Error rotating refresh token: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	refreshToken := auth.MakeRefreshToken()
+	refreshTokenParams := db.SaveRefreshTokenParams{
+		TokenHash:  auth.HashToken(refreshToken),
+		UserID:     rotated.UserID,
+		ExpiresAt:  time.Now().Add(cfg.RefreshTokenTTL),
+		FamilyID:   rotated.FamilyID,
+		UserAgent:  userAgent(r),
+		IpAddress:  clientIP(r),
+		SignedInAt: rotated.SignedInAt,
+	}
+	if _, err := qtx.SaveRefreshToken(r.Context(), refreshTokenParams); err != nil {
+		log.Printf("Error saving the refresh token: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	tokenVersion, err := qtx.GetUserTokenVersion(r.Context(), rotated.UserID)
+	if err != nil {
+		log.Printf("Error retriaving token version of user '%s': %s", rotated.UserID.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
 	accessToken, err := auth.MakeJWT(
-		userRow.UserID,
-		cfg.JWTSecret,
+		rotated.UserID,
+		tokenVersion,
+		rotated.FamilyID,
+		cfg.JWTKeys,
 		time.Hour,
 	)
 
@@ -278,10 +514,20 @@ func (cfg ApiConfig) RefreshToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing refresh token rotation: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
 	type response struct {
-		Token string `json:"token"`
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	resp := response{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
 	}
-	resp := response{Token: accessToken}
 	data, err := json.Marshal(resp)
 	if err != nil {
 		log.Printf("Error marshalling JSON: %s", err)
@@ -292,7 +538,57 @@ func (cfg ApiConfig) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	w.Write(data)
 }
 
-func (cfg ApiConfig) RevokeToken(w http.ResponseWriter, r *http.Request) {
+// rejectRefreshToken answers 401 for a token that could not be rotated. If
+// the token had already been rotated, its family is revoked before
+// answering.
+func (cfg *ApiConfig) rejectRefreshToken(w http.ResponseWriter, r *http.Request, qtx *db.Queries, tx *sql.Tx, tokenHash string) {
+	family, err := qtx.GetRefreshTokenFamily(r.Context(), tokenHash)
+	if err != nil {
+		log.Printf("Error searching refresh token: %s", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if !family.RotatedAt.Valid {
+		if family.RevokedAt.Valid {
+			log.Printf("Refresh token of user '%s' was revoked at: %s", family.UserID.String(), family.RevokedAt.Time)
+		} else {
+			log.Printf("Refresh token of user '%s' expired at: %s", family.UserID.String(), family.ExpiresAt)
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	revoked, err := qtx.RevokeRefreshTokenFamily(r.Context(), family.FamilyID)
+	if err != nil {
+		log.Printf("Error revoking refresh token family '%s': %s", family.FamilyID.String(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err = qtx.CreateSecurityEvent(r.Context(), db.CreateSecurityEventParams{
+		UserID:    family.UserID,
+		EventType: securityEventRefreshTokenReuse,
+		Details: fmt.Sprintf("refresh token rotated at %s was reused; revoked %d token(s) in family %s",
+			family.RotatedAt.Time.Format(time.RFC3339), revoked, family.FamilyID.String()),
+	})
+	if err != nil {
+		log.Printf("Error recording security event: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing refresh token family revocation: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Reuse of rotated refresh token detected for user '%s', family '%s' revoked", family.UserID.String(), family.FamilyID.String())
+	w.WriteHeader(http.StatusUnauthorized)
+}
+
+func (cfg *ApiConfig) RevokeToken(w http.ResponseWriter, r *http.Request) {
 	token, err := auth.GetBearerToken(r.Header)
 	if err != nil {
 		log.Printf("Refesh token not found: %s", err)
@@ -300,10 +596,14 @@ func (cfg ApiConfig) RevokeToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := cfg.Db.RevokeToken(r.Context(), token)
-	rows, errorQuery := result.RowsAffected()
-	if err != nil || errorQuery != nil || rows == 0 {
-		log.Printf("Error revoking refresh token '%s': %s", token, err)
+	result, err := cfg.Db.RevokeToken(r.Context(), auth.HashToken(token))
+	if err != nil {
+		log.Printf("Error revoking refresh token: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		log.Printf("Refresh token to revoke not found: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -11,79 +12,153 @@ import (
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type TokenType string
 
 const (
 	TokenTypeAccess TokenType = "chirpy-access"
+	// TokenTypeMFA tokens only prove the password was right and can be
+	// exchanged for a session together with a second factor.
+	TokenTypeMFA TokenType = "chirpy-mfa"
 )
 
-func HashPassword(password string) (string, error) {
-	minLenght := 1
-	if len(password) < minLenght {
-		return "", fmt.Errorf("The password can be less than %d", minLenght)
+func MakeRefreshToken() string {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return hex.EncodeToString(key)
+}
+
+// HashToken returns the hex SHA-256 digest under which an opaque token is
+// stored. Refresh tokens are random, so a plain digest is enough; there is
+// nothing to brute force.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ErrTokenRevoked is returned by ValidateJWT for a token issued before the
+// user's token version was last bumped.
+var ErrTokenRevoked = errors.New("token has been revoked")
+
+// TokenVersionFunc returns the current token version of a user.
+type TokenVersionFunc func(userID uuid.UUID) (int32, error)
+
+type accessClaims struct {
+	jwt.RegisteredClaims
+	Version int32 `json:"ver"`
+	// SessionID is the refresh token family the access token was issued
+	// for. MFA tokens have none.
+	SessionID string `json:"sid,omitempty"`
+}
+
+func MakeJWT(userID uuid.UUID, tokenVersion int32, sessionID uuid.UUID, keys *KeyRing, expiresIn time.Duration) (string, error) {
+	claims := newClaims(TokenTypeAccess, userID, expiresIn)
+	claims.Version = tokenVersion
+	claims.SessionID = sessionID.String()
+	return keys.sign(claims)
+}
+
+// MakeMFAToken makes a token with a unique ID (jti), so that the server
+// can refuse it once it has been exchanged for a session.
+func MakeMFAToken(userID uuid.UUID, keys *KeyRing, expiresIn time.Duration) (string, error) {
+	claims := newClaims(TokenTypeMFA, userID, expiresIn)
+	claims.ID = uuid.NewString()
+	return keys.sign(claims)
+}
+
+func newClaims(tokenType TokenType, userID uuid.UUID, expiresIn time.Duration) accessClaims {
+	return accessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    string(tokenType),
+			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
+			Subject:   userID.String(),
+		},
 	}
-	b, err := bcrypt.GenerateFromPassword([]byte(password), 0)
+}
+
+// ValidateJWT checks the signature, expiry and issuer of an access token,
+// using the key of the ring named by its kid header, and returns the user
+// and session it was issued for. The session is uuid.Nil for tokens from
+// before sessions were recorded in them. When currentVersion is not nil,
+// the token's version must also match the user's current one.
+func ValidateJWT(tokenString string, keys *KeyRing, currentVersion TokenVersionFunc) (userID, sessionID uuid.UUID, err error) {
+	id, claims, err := parseToken(TokenTypeAccess, tokenString, keys)
 	if err != nil {
-		return "", err
+		return uuid.Nil, uuid.Nil, err
 	}
-	return string(b), nil
-}
 
-func CheckPasswordHash(password, hash string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if claims.SessionID != "" {
+		sessionID, err = uuid.Parse(claims.SessionID)
+		if err != nil {
+			return uuid.Nil, uuid.Nil, fmt.Errorf("invalid session ID: %w", err)
+		}
+	}
+
+	if currentVersion != nil {
+		version, err := currentVersion(id)
+		if err != nil {
+			return uuid.Nil, uuid.Nil, err
+		}
+		if claims.Version != version {
+			return uuid.Nil, uuid.Nil, ErrTokenRevoked
+		}
+	}
+	return id, sessionID, nil
+
 }
 
-func MakeRefreshToken() string {
-	key := make([]byte, 32)
-	rand.Read(key)
-	return hex.EncodeToString(key)
+// MFAToken is what a valid token from MakeMFAToken says.
+type MFAToken struct {
+	UserID    uuid.UUID
+	ID        string
+	ExpiresAt time.Time
 }
 
-func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
-	claims := jwt.RegisteredClaims{
-		Issuer:    string(TokenTypeAccess),
-		IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
-		Subject:   userID.String(),
+// ValidateMFAToken checks a token made by MakeMFAToken. Whether its ID has
+// been used already is up to the caller.
+func ValidateMFAToken(tokenString string, keys *KeyRing) (MFAToken, error) {
+	id, claims, err := parseToken(TokenTypeMFA, tokenString, keys)
+	if err != nil {
+		return MFAToken{}, err
+	}
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return MFAToken{}, errors.New("MFA token has no ID or expiry")
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(tokenSecret))
+	return MFAToken{UserID: id, ID: claims.ID, ExpiresAt: claims.ExpiresAt.Time}, nil
 }
 
-func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	claimsStruct := jwt.RegisteredClaims{}
+func parseToken(tokenType TokenType, tokenString string, keys *KeyRing) (uuid.UUID, accessClaims, error) {
+	claimsStruct := accessClaims{}
 	token, err := jwt.ParseWithClaims(
 		tokenString,
 		&claimsStruct,
-		func(t *jwt.Token) (interface{}, error) { return []byte(tokenSecret), nil },
+		keys.keyFunc,
 	)
 
 	if err != nil {
-		return uuid.Nil, err
+		return uuid.Nil, claimsStruct, err
 	}
 
 	userIDString, err := token.Claims.GetSubject()
 	if err != nil {
-		return uuid.Nil, err
+		return uuid.Nil, claimsStruct, err
 	}
 
 	issuer, err := token.Claims.GetIssuer()
 	if err != nil {
-		return uuid.Nil, err
+		return uuid.Nil, claimsStruct, err
 	}
-	if issuer != string(TokenTypeAccess) {
-		return uuid.Nil, errors.New("invalid issuer")
+	if issuer != string(tokenType) {
+		return uuid.Nil, claimsStruct, errors.New("invalid issuer")
 	}
 
 	id, err := uuid.Parse(userIDString)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("invalid user ID: %w", err)
+		return uuid.Nil, claimsStruct, fmt.Errorf("invalid user ID: %w", err)
 	}
-	return id, nil
-
+	return id, claimsStruct, nil
 }
 
 func GetBearerToken(headers http.Header) (string, error) {
@@ -65,41 +65,72 @@ func TestCheckPasswordHash(t *testing.T) {
 
 func TestValidateJWT(t *testing.T) {
 	userID := uuid.New()
-	validToken, _ := MakeJWT(userID, "secret", time.Hour)
+	sessionID := uuid.New()
+	keys, _ := NewKeyRing(NewHMACKey(LegacyKeyID, []byte("secret")))
+	otherKeys, _ := NewKeyRing(NewHMACKey(LegacyKeyID, []byte("wrong_secret")))
+	validToken, _ := MakeJWT(userID, 2, sessionID, keys, time.Hour)
+	mfaToken, _ := MakeMFAToken(userID, keys, time.Hour)
+	currentVersion := func(version int32) TokenVersionFunc {
+		return func(uuid.UUID) (int32, error) { return version, nil }
+	}
 
 	tests := []struct {
-		name        string
-		tokenString string
-		tokenSecret string
-		wantUserID  uuid.UUID
-		wantErr     bool
+		name           string
+		tokenString    string
+		keys           *KeyRing
+		currentVersion TokenVersionFunc
+		wantUserID     uuid.UUID
+		wantErr        bool
 	}{
 		{
 			name:        "Valid token",
 			tokenString: validToken,
-			tokenSecret: "secret",
+			keys:        keys,
 			wantUserID:  userID,
 			wantErr:     false,
 		},
 		{
 			name:        "Invalid token",
 			tokenString: "invalid.token.string",
-			tokenSecret: "secret",
+			keys:        keys,
 			wantUserID:  uuid.Nil,
 			wantErr:     true,
 		},
 		{
-			name:        "Wrong secret",
+			name:        "Wrong key",
 			tokenString: validToken,
-			tokenSecret: "wrong_secret",
+			keys:        otherKeys,
 			wantUserID:  uuid.Nil,
 			wantErr:     true,
 		},
+		{
+			name:        "MFA token",
+			tokenString: mfaToken,
+			keys:        keys,
+			wantUserID:  uuid.Nil,
+			wantErr:     true,
+		},
+		{
+			name:           "Current token version",
+			tokenString:    validToken,
+			keys:           keys,
+			currentVersion: currentVersion(2),
+			wantUserID:     userID,
+			wantErr:        false,
+		},
+		{
+			name:           "Outdated token version",
+			tokenString:    validToken,
+			keys:           keys,
+			currentVersion: currentVersion(3),
+			wantUserID:     uuid.Nil,
+			wantErr:        true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotUserID, err := ValidateJWT(tt.tokenString, tt.tokenSecret)
+			gotUserID, gotSessionID, err := ValidateJWT(tt.tokenString, tt.keys, tt.currentVersion)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ValidateJWT() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -107,6 +138,75 @@ func TestValidateJWT(t *testing.T) {
 			if gotUserID != tt.wantUserID {
 				t.Errorf("ValidateJWT() gotUserID = %v, want %v", gotUserID, tt.wantUserID)
 			}
+			if !tt.wantErr && gotSessionID != sessionID {
+				t.Errorf("ValidateJWT() gotSessionID = %v, want %v", gotSessionID, sessionID)
+			}
+		})
+	}
+}
+
+func TestValidateMFAToken(t *testing.T) {
+	userID := uuid.New()
+	keys, _ := NewKeyRing(NewHMACKey(LegacyKeyID, []byte("secret")))
+	otherKeys, _ := NewKeyRing(NewHMACKey(LegacyKeyID, []byte("wrong_secret")))
+	mfaToken, _ := MakeMFAToken(userID, keys, time.Hour)
+	otherMFAToken, _ := MakeMFAToken(userID, keys, time.Hour)
+	accessToken, _ := MakeJWT(userID, 1, uuid.New(), keys, time.Hour)
+	noIDToken, _ := keys.sign(newClaims(TokenTypeMFA, userID, time.Hour))
+
+	other, err := ValidateMFAToken(otherMFAToken, keys)
+	if err != nil {
+		t.Fatalf("ValidateMFAToken() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		token      string
+		keys       *KeyRing
+		wantUserID uuid.UUID
+		wantErr    bool
+	}{
+		{
+			name:       "Valid token",
+			token:      mfaToken,
+			keys:       keys,
+			wantUserID: userID,
+		},
+		{
+			name:    "Wrong key",
+			token:   mfaToken,
+			keys:    otherKeys,
+			wantErr: true,
+		},
+		{
+			name:    "Access token",
+			token:   accessToken,
+			keys:    keys,
+			wantErr: true,
+		},
+		{
+			name:    "No token ID",
+			token:   noIDToken,
+			keys:    keys,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateMFAToken(tt.token, tt.keys)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateMFAToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.UserID != tt.wantUserID {
+				t.Errorf("ValidateMFAToken() UserID = %v, want %v", got.UserID, tt.wantUserID)
+			}
+			if got.ID == "" || got.ID == other.ID {
+				t.Errorf("ValidateMFAToken() ID = %q, want a unique ID", got.ID)
+			}
 		})
 	}
 }
@@ -155,3 +255,30 @@ func TestGetBearerToken(t *testing.T) {
 		})
 	}
 }
+
+func TestHashToken(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+		want  string
+	}{
+		{
+			name:  "Known digest",
+			token: "abc",
+			want:  "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+		},
+		{
+			name:  "Empty token",
+			token: "",
+			want:  "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HashToken(tt.token); got != tt.want {
+				t.Errorf("HashToken() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package auth
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+)
+
+const maxEmailLength = 254
+
+// ValidateEmail checks that s is a bare RFC 5322 address such as
+// walt@breakingbad.com, without a display name or angle brackets, and
+// returns it with surrounding space trimmed.
+func ValidateEmail(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || len(s) > maxEmailLength {
+		return "", errors.New("email must be between 1 and 254 characters")
+	}
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Address != s || addr.Name != "" {
+		return "", errors.New("email must be a valid address like name@example.com")
+	}
+	if !strings.Contains(s[strings.LastIndex(s, "@")+1:], ".") {
+		return "", errors.New("email domain must be fully qualified")
+	}
+	return s, nil
+}
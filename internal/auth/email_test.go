@@ -0,0 +1,74 @@
+package auth
+
+import "testing"
+
+func TestValidateEmail(t *testing.T) {
+	tests := []struct {
+		name    string
+		email   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "Plain address",
+			email:   "walt@breakingbad.com",
+			want:    "walt@breakingbad.com",
+			wantErr: false,
+		},
+		{
+			name:    "Surrounding space",
+			email:   "  walt@breakingbad.com ",
+			want:    "walt@breakingbad.com",
+			wantErr: false,
+		},
+		{
+			name:    "Plus addressing",
+			email:   "walt+chirpy@breakingbad.com",
+			want:    "walt+chirpy@breakingbad.com",
+			wantErr: false,
+		},
+		{
+			name:    "Empty",
+			email:   "",
+			wantErr: true,
+		},
+		{
+			name:    "No at sign",
+			email:   "walt.breakingbad.com",
+			wantErr: true,
+		},
+		{
+			name:    "Display name",
+			email:   "Walter White <walt@breakingbad.com>",
+			wantErr: true,
+		},
+		{
+			name:    "Two addresses",
+			email:   "walt@breakingbad.com, jesse@breakingbad.com",
+			wantErr: true,
+		},
+		{
+			name:    "Unqualified domain",
+			email:   "walt@localhost",
+			wantErr: true,
+		},
+		{
+			name:    "Header injection",
+			email:   "walt@breakingbad.com\r\nBcc: jesse@breakingbad.com",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateEmail(tt.email)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateEmail() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ValidateEmail() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,269 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// LegacyKeyID names the HS256 key built from JWT_SECRET. Tokens issued
+// before kid headers existed are verified with it.
+const LegacyKeyID = "legacy"
+
+// Key is one entry of a KeyRing. Only the active key signs; the others
+// verify tokens until their RetireAt time.
+type Key struct {
+	ID       string
+	RetireAt time.Time
+
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+func NewHMACKey(id string, secret []byte) *Key {
+	return &Key{
+		ID:        id,
+		method:    jwt.SigningMethodHS256,
+		signKey:   secret,
+		verifyKey: secret,
+	}
+}
+
+// ParseKeyPEM reads an RSA or Ed25519 key. A private key can sign and
+// verify, a public key can only verify.
+func ParseKeyPEM(id string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key %q: no PEM data found", id)
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("key %q: unsupported PEM block %q", id, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("key %q: %w", id, err)
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &Key{ID: id, method: jwt.SigningMethodRS256, signKey: k, verifyKey: &k.PublicKey}, nil
+	case *rsa.PublicKey:
+		return &Key{ID: id, method: jwt.SigningMethodRS256, verifyKey: k}, nil
+	case ed25519.PrivateKey:
+		return &Key{ID: id, method: jwt.SigningMethodEdDSA, signKey: k, verifyKey: k.Public()}, nil
+	case ed25519.PublicKey:
+		return &Key{ID: id, method: jwt.SigningMethodEdDSA, verifyKey: k}, nil
+	default:
+		return nil, fmt.Errorf("key %q: unsupported key type %T", id, parsed)
+	}
+}
+
+func (k *Key) Algorithm() string {
+	return k.method.Alg()
+}
+
+func (k *Key) retired(now time.Time) bool {
+	return !k.RetireAt.IsZero() && !now.Before(k.RetireAt)
+}
+
+// KeyRing holds the key new tokens are signed with and the keys older
+// tokens may still be verified with.
+type KeyRing struct {
+	active *Key
+	keys   map[string]*Key
+}
+
+// NewKeyRing builds a ring signing with active. The other keys only verify
+// and should have RetireAt set to when the last token they signed expires.
+func NewKeyRing(active *Key, others ...*Key) (*KeyRing, error) {
+	if active.signKey == nil {
+		return nil, fmt.Errorf("key %q: active key has no private part", active.ID)
+	}
+	if !active.RetireAt.IsZero() {
+		return nil, fmt.Errorf("key %q: active key can't be retired", active.ID)
+	}
+
+	ring := &KeyRing{
+		active: active,
+		keys:   map[string]*Key{active.ID: active},
+	}
+	for _, k := range others {
+		if _, ok := ring.keys[k.ID]; ok {
+			return nil, fmt.Errorf("key %q: duplicate key ID", k.ID)
+		}
+		ring.keys[k.ID] = k
+	}
+	return ring, nil
+}
+
+type keyRingFile struct {
+	Active string `json:"active"`
+	Keys   []struct {
+		ID             string     `json:"kid"`
+		PrivateKeyFile string     `json:"private_key_file"`
+		PublicKeyFile  string     `json:"public_key_file"`
+		Secret         string     `json:"secret"`
+		RetireAt       *time.Time `json:"retire_at"`
+	} `json:"keys"`
+}
+
+// LoadKeyRing reads a JSON key ring description. Key file paths are
+// relative to the description itself. Keys whose retire_at has passed are
+// left out.
+func LoadKeyRing(path string) (*KeyRing, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	desc := keyRingFile{}
+	if err := json.Unmarshal(data, &desc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	now := time.Now()
+	var active *Key
+	others := []*Key{}
+	for _, entry := range desc.Keys {
+		var key *Key
+		switch {
+		case entry.Secret != "":
+			key = NewHMACKey(entry.ID, []byte(entry.Secret))
+		case entry.PrivateKeyFile != "" || entry.PublicKeyFile != "":
+			file := entry.PrivateKeyFile
+			if file == "" {
+				file = entry.PublicKeyFile
+			}
+			if !filepath.IsAbs(file) {
+				file = filepath.Join(filepath.Dir(path), file)
+			}
+			pemData, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			key, err = ParseKeyPEM(entry.ID, pemData)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("key %q: no key material", entry.ID)
+		}
+
+		if entry.RetireAt != nil {
+			key.RetireAt = *entry.RetireAt
+		}
+		if key.retired(now) {
+			continue
+		}
+		if entry.ID == desc.Active {
+			active = key
+		} else {
+			others = append(others, key)
+		}
+	}
+
+	if active == nil {
+		return nil, fmt.Errorf("%s: active key %q not found", path, desc.Active)
+	}
+	return NewKeyRing(active, others...)
+}
+
+func (kr *KeyRing) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(kr.active.method, claims)
+	token.Header["kid"] = kr.active.ID
+	return token.SignedString(kr.active.signKey)
+}
+
+// keyFunc picks the verification key by kid. The token's alg must be the
+// one the key was made for, so a public RSA key can never be used as an
+// HMAC secret.
+func (kr *KeyRing) keyFunc(t *jwt.Token) (interface{}, error) {
+	kid := LegacyKeyID
+	if v, ok := t.Header["kid"]; ok {
+		s, ok := v.(string)
+		if !ok {
+			return nil, errors.New("malformed kid header")
+		}
+		kid = s
+	}
+
+	key, ok := kr.keys[kid]
+	if !ok || key.retired(time.Now()) {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if t.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("key %q does not sign with %s", kid, t.Method.Alg())
+	}
+	return key.verifyKey, nil
+}
+
+// JWK is a public key in RFC 7517 form.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS lists the public keys of the ring that are still in use. HMAC keys
+// are secret and never published.
+func (kr *KeyRing) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	now := time.Now()
+	for _, key := range kr.keys {
+		if key.retired(now) {
+			continue
+		}
+		if jwk, ok := publicJWK(key); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].KeyID < set.Keys[j].KeyID })
+	return set
+}
+
+func publicJWK(key *Key) (JWK, bool) {
+	jwk := JWK{KeyID: key.ID, Use: "sig", Algorithm: key.method.Alg()}
+	switch k := key.verifyKey.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(k.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(k)
+	default:
+		return jwk, false
+	}
+	return jwk, true
+}
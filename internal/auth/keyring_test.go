@@ -0,0 +1,241 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func pemKey(t *testing.T, blockType string, der []byte, err error) []byte {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("marshalling key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+}
+
+func testKeys(t *testing.T) (rsaPrivate, rsaPublic, edPrivate []byte) {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating RSA key: %v", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generating Ed25519 key: %v", err)
+	}
+
+	rsaPrivate = pemKey(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey), nil)
+	der, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	rsaPublic = pemKey(t, "PUBLIC KEY", der, err)
+	der, err = x509.MarshalPKCS8PrivateKey(edKey)
+	edPrivate = pemKey(t, "PRIVATE KEY", der, err)
+	return
+}
+
+func mustParseKey(t *testing.T, id string, data []byte) *Key {
+	t.Helper()
+	key, err := ParseKeyPEM(id, data)
+	if err != nil {
+		t.Fatalf("ParseKeyPEM(%q) error = %v", id, err)
+	}
+	return key
+}
+
+func TestKeyRingRotation(t *testing.T) {
+	rsaPrivate, rsaPublic, edPrivate := testKeys(t)
+	userID := uuid.New()
+
+	legacy, _ := NewKeyRing(NewHMACKey(LegacyKeyID, []byte("secret")))
+	legacyToken, _ := MakeJWT(userID, 0, uuid.New(), legacy, time.Hour)
+
+	old, _ := NewKeyRing(mustParseKey(t, "rsa-1", rsaPrivate))
+	oldToken, _ := MakeJWT(userID, 0, uuid.New(), old, time.Hour)
+
+	// A token from before kid headers were added.
+	noKidToken, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Issuer:    string(TokenTypeAccess),
+		Subject:   userID.String(),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}).SignedString([]byte("secret"))
+
+	// An attacker signing with the published RSA key as an HMAC secret.
+	confused := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Issuer:    string(TokenTypeAccess),
+		Subject:   userID.String(),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	})
+	confused.Header["kid"] = "rsa-1"
+	confusedToken, _ := confused.SignedString(rsaPublic)
+
+	retiring := mustParseKey(t, "rsa-1", rsaPublic)
+	retiring.RetireAt = time.Now().Add(time.Hour)
+	legacyKey := NewHMACKey(LegacyKeyID, []byte("secret"))
+	legacyKey.RetireAt = time.Now().Add(time.Hour)
+	current, err := NewKeyRing(mustParseKey(t, "ed-1", edPrivate), retiring, legacyKey)
+	if err != nil {
+		t.Fatalf("NewKeyRing() error = %v", err)
+	}
+	currentToken, _ := MakeJWT(userID, 0, uuid.New(), current, time.Hour)
+
+	retired := mustParseKey(t, "rsa-1", rsaPublic)
+	retired.RetireAt = time.Now().Add(-time.Minute)
+	afterOverlap, _ := NewKeyRing(mustParseKey(t, "ed-1", edPrivate), retired)
+
+	tests := []struct {
+		name        string
+		tokenString string
+		keys        *KeyRing
+		wantErr     bool
+	}{
+		{
+			name:        "Token signed by the active key",
+			tokenString: currentToken,
+			keys:        current,
+			wantErr:     false,
+		},
+		{
+			name:        "Token signed by a key in its overlap window",
+			tokenString: oldToken,
+			keys:        current,
+			wantErr:     false,
+		},
+		{
+			name:        "Token signed by a retired key",
+			tokenString: oldToken,
+			keys:        afterOverlap,
+			wantErr:     true,
+		},
+		{
+			name:        "Legacy token with kid",
+			tokenString: legacyToken,
+			keys:        current,
+			wantErr:     false,
+		},
+		{
+			name:        "Legacy token without kid",
+			tokenString: noKidToken,
+			keys:        current,
+			wantErr:     false,
+		},
+		{
+			name:        "Legacy token after the legacy key is gone",
+			tokenString: noKidToken,
+			keys:        afterOverlap,
+			wantErr:     true,
+		},
+		{
+			name:        "Algorithm confusion",
+			tokenString: confusedToken,
+			keys:        current,
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotUserID, _, err := ValidateJWT(tt.tokenString, tt.keys, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateJWT() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && gotUserID != userID {
+				t.Errorf("ValidateJWT() gotUserID = %v, want %v", gotUserID, userID)
+			}
+		})
+	}
+}
+
+func TestNewKeyRing(t *testing.T) {
+	_, rsaPublic, edPrivate := testKeys(t)
+	retired := NewHMACKey("hmac", []byte("secret"))
+	retired.RetireAt = time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name    string
+		active  *Key
+		others  []*Key
+		wantErr bool
+	}{
+		{
+			name:    "Private active key",
+			active:  mustParseKey(t, "ed-1", edPrivate),
+			wantErr: false,
+		},
+		{
+			name:    "Public active key",
+			active:  mustParseKey(t, "rsa-1", rsaPublic),
+			wantErr: true,
+		},
+		{
+			name:    "Retired active key",
+			active:  retired,
+			wantErr: true,
+		},
+		{
+			name:    "Duplicate key ID",
+			active:  mustParseKey(t, "ed-1", edPrivate),
+			others:  []*Key{mustParseKey(t, "ed-1", edPrivate)},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewKeyRing(tt.active, tt.others...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewKeyRing() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadKeyRingAndJWKS(t *testing.T) {
+	_, rsaPublic, edPrivate := testKeys(t)
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "ed-1.pem"), edPrivate, 0o600)
+	os.WriteFile(filepath.Join(dir, "rsa-1.pub.pem"), rsaPublic, 0o600)
+	os.WriteFile(filepath.Join(dir, "keys.json"), []byte(`{
+		"active": "ed-1",
+		"keys": [
+			{"kid": "ed-1", "private_key_file": "ed-1.pem"},
+			{"kid": "rsa-1", "public_key_file": "rsa-1.pub.pem", "retire_at": "2999-01-01T00:00:00Z"},
+			{"kid": "rsa-0", "public_key_file": "rsa-1.pub.pem", "retire_at": "2000-01-01T00:00:00Z"},
+			{"kid": "legacy", "secret": "secret", "retire_at": "2999-01-01T00:00:00Z"}
+		]
+	}`), 0o600)
+
+	ring, err := LoadKeyRing(filepath.Join(dir, "keys.json"))
+	if err != nil {
+		t.Fatalf("LoadKeyRing() error = %v", err)
+	}
+
+	token, _ := MakeJWT(uuid.New(), 0, uuid.New(), ring, time.Hour)
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &jwt.RegisteredClaims{})
+	if err != nil {
+		t.Fatalf("ParseUnverified() error = %v", err)
+	}
+	if parsed.Header["kid"] != "ed-1" || parsed.Method.Alg() != "EdDSA" {
+		t.Errorf("token header = %v, want kid ed-1 signed with EdDSA", parsed.Header)
+	}
+
+	set := ring.JWKS()
+	if len(set.Keys) != 2 {
+		t.Fatalf("JWKS() returned %d keys, want 2: %+v", len(set.Keys), set.Keys)
+	}
+	if k := set.Keys[0]; k.KeyID != "ed-1" || k.KeyType != "OKP" || k.Curve != "Ed25519" || k.X == "" {
+		t.Errorf("JWKS()[0] = %+v, want the Ed25519 key", k)
+	}
+	if k := set.Keys[1]; k.KeyID != "rsa-1" || k.KeyType != "RSA" || k.E != "AQAB" || k.N == "" {
+		t.Errorf("JWKS()[1] = %+v, want the RSA key", k)
+	}
+}
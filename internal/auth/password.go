@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Argon2Params are the Argon2id settings new hashes are made with. They are
+// stored in every hash, so changing them only affects new hashes; older ones
+// are upgraded by NeedsRehash at the next login.
+type Argon2Params struct {
+	Memory  uint32 // KiB
+	Time    uint32
+	Threads uint8
+	SaltLen uint32
+	KeyLen  uint32
+}
+
+// DefaultArgon2Params follow the second recommended option of RFC 9106.
+var DefaultArgon2Params = Argon2Params{
+	Memory:  64 * 1024,
+	Time:    3,
+	Threads: 4,
+	SaltLen: 16,
+	KeyLen:  32,
+}
+
+var errMalformedHash = errors.New("malformed password hash")
+
+// argon2Slots caps how many Argon2id hashes run at once. Each one takes
+// Memory KiB (64 MiB by default), so a burst of logins would otherwise use
+// as much memory as it likes; callers beyond the cap wait their turn.
+var argon2Slots = make(chan struct{}, runtime.GOMAXPROCS(0))
+
+func argon2IDKey(password, salt []byte, p Argon2Params) []byte {
+	argon2Slots <- struct{}{}
+	defer func() { <-argon2Slots }()
+	return argon2.IDKey(password, salt, p.Time, p.Memory, p.Threads, p.KeyLen)
+}
+
+// HashPassword hashes password with Argon2id into the PHC string format,
+// $argon2id$v=19$m=65536,t=3,p=4$<salt>$<hash>. Check the password against
+// a PasswordPolicy first.
+func HashPassword(password string) (string, error) {
+	if password == "" {
+		return "", errors.New("password can't be empty")
+	}
+	p := DefaultArgon2Params
+	salt := make([]byte, p.SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2IDKey([]byte(password), salt, p)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Time, p.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// CheckPasswordHash compares password with a hash from HashPassword, or with
+// a bcrypt hash made before Argon2id was used.
+func CheckPasswordHash(password, hash string) error {
+	if !strings.HasPrefix(hash, "$argon2id$") {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	}
+
+	p, salt, key, err := decodeArgon2Hash(hash)
+	if err != nil {
+		return err
+	}
+	got := argon2IDKey([]byte(password), salt, p)
+	if subtle.ConstantTimeCompare(got, key) != 1 {
+		return errors.New("password does not match hash")
+	}
+	return nil
+}
+
+// NeedsRehash reports whether hash was made with another algorithm or other
+// parameters than HashPassword uses now. Rehash the password after a
+// successful CheckPasswordHash when it does.
+func NeedsRehash(hash string) bool {
+	p, salt, _, err := decodeArgon2Hash(hash)
+	if err != nil {
+		return true
+	}
+	want := DefaultArgon2Params
+	return p.Memory != want.Memory || p.Time != want.Time || p.Threads != want.Threads ||
+		p.KeyLen != want.KeyLen || uint32(len(salt)) != want.SaltLen
+}
+
+func decodeArgon2Hash(hash string) (Argon2Params, []byte, []byte, error) {
+	p := Argon2Params{}
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return p, nil, nil, errMalformedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, errMalformedHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Time, &p.Threads); err != nil {
+		return p, nil, nil, errMalformedHash
+	}
+	if p.Memory == 0 || p.Time == 0 || p.Threads == 0 {
+		return p, nil, nil, errMalformedHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, errMalformedHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, errMalformedHash
+	}
+	p.SaltLen = uint32(len(salt))
+	p.KeyLen = uint32(len(key))
+	return p, salt, key, nil
+}
+
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, _ := HashPassword("not a real password")
+	return hash
+})
+
+// CheckDummyPassword spends as long as CheckPasswordHash does against a
+// real hash. Call it when there is no user to check against so that the
+// response time doesn't reveal which emails have accounts.
+func CheckDummyPassword(password string) {
+	CheckPasswordHash(password, dummyPasswordHash())
+}
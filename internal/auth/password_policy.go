@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxPasswordBytes is the most bcrypt looks at. Longer passwords are
+// refused rather than silently cut, so they stay checkable by either hasher.
+const MaxPasswordBytes = 72
+
+// PasswordPolicy decides which new passwords are acceptable.
+type PasswordPolicy struct {
+	MinLength int // in characters
+	MaxBytes  int
+	// Breached lists passwords known from data breaches. It may be nil.
+	Breached *BreachedPasswords
+}
+
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength: 8,
+	MaxBytes:  MaxPasswordBytes,
+}
+
+// Validate returns an error meant for the client when password breaks the
+// policy.
+func (p PasswordPolicy) Validate(password string) error {
+	if utf8.RuneCountInString(password) < p.MinLength {
+		return fmt.Errorf("password must be at least %d characters", p.MinLength)
+	}
+	if len(password) > p.MaxBytes {
+		return fmt.Errorf("password must be at most %d bytes", p.MaxBytes)
+	}
+	if p.Breached != nil && p.Breached.Contains(password) {
+		return fmt.Errorf("password has appeared in a data breach, choose another one")
+	}
+	return nil
+}
+
+// BreachedPasswords is a local copy of a breached password list such as
+// the Pwned Passwords download, ordered by hash. The list is far too big to
+// hold in memory, so it stays on disk and is binary searched.
+type BreachedPasswords struct {
+	f    *os.File
+	size int64
+}
+
+// breachedLineMax is more than a digest, a count and a line ending take.
+const breachedLineMax = 128
+
+// breachedCheckLines is how many lines OpenBreachedPasswords checks.
+const breachedCheckLines = 1000
+
+// OpenBreachedPasswords opens a file with one uppercase or lowercase SHA-1
+// hex digest per line, optionally followed by ":count", sorted by digest.
+// Only the first lines are checked here; lookups in an unsorted file miss.
+func OpenBreachedPasswords(path string) (*BreachedPasswords, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(f)
+	previous := ""
+	for line := 1; line <= breachedCheckLines && scanner.Scan(); line++ {
+		digest, ok := breachedDigest(scanner.Text())
+		if !ok {
+			f.Close()
+			return nil, fmt.Errorf("%s:%d: not a SHA-1 digest", path, line)
+		}
+		if digest < previous {
+			f.Close()
+			return nil, fmt.Errorf("%s:%d: digests are not sorted", path, line)
+		}
+		previous = digest
+	}
+	if err := scanner.Err(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &BreachedPasswords{f: f, size: info.Size()}, nil
+}
+
+// breachedDigest returns the uppercase digest on a line of the list.
+func breachedDigest(line string) (string, bool) {
+	digest, _, _ := strings.Cut(strings.TrimSpace(line), ":")
+	if _, err := hex.DecodeString(digest); err != nil || len(digest) != 2*sha1.Size {
+		return "", false
+	}
+	return strings.ToUpper(digest), true
+}
+
+// Contains reports whether password is on the list. A read error is
+// logged and treated as not listed, so a broken disk doesn't stop signups.
+func (b *BreachedPasswords) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	target := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	// A listed digest's line always starts within [lo, hi).
+	lo, hi := int64(0), b.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		start, digest, err := b.lineAt(mid)
+		if err != nil {
+			log.Printf("Error reading breached passwords: %s", err)
+			return false
+		}
+		switch {
+		case start >= hi || digest > target:
+			hi = mid
+		case digest < target:
+			lo = start + 1
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// lineAt finds the first line starting at or after off, and returns where
+// it starts and its digest. start is b.size when there is no such line.
+func (b *BreachedPasswords) lineAt(off int64) (int64, string, error) {
+	buf := make([]byte, 2*breachedLineMax)
+	start := off
+	if off > 0 {
+		// Read from the byte before off, so a line starting at off is seen
+		// after the newline ending the one before it.
+		n, err := b.f.ReadAt(buf, off-1)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return 0, "", err
+		}
+		i := bytes.IndexByte(buf[:n], '\n')
+		if i < 0 {
+			return b.size, "", nil
+		}
+		start = off + int64(i)
+	}
+	if start >= b.size {
+		return b.size, "", nil
+	}
+
+	n, err := b.f.ReadAt(buf[:breachedLineMax], start)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 0, "", err
+	}
+	line, _, _ := bytes.Cut(buf[:n], []byte("\n"))
+	digest, ok := breachedDigest(string(line))
+	if !ok {
+		return 0, "", fmt.Errorf("malformed line at byte %d", start)
+	}
+	return start, digest, nil
+}
@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	// SHA-1 of "password1" and, in lowercase, of "P@ssw0rd", among enough
+	// others to make the search take a few steps.
+	lines := []string{
+		"E38AD214943DAAD1D64C102FAEC29DE4AFE9DA3D:2427158",
+		"21bd12dc183f740ee76f27b78eb39c8ad972a757:61",
+	}
+	for i := 0; i < 500; i++ {
+		sum := sha1.Sum([]byte(fmt.Sprintf("filler %d", i)))
+		lines = append(lines, fmt.Sprintf("%X:%d", sum, i))
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		return strings.ToUpper(lines[i]) < strings.ToUpper(lines[j])
+	})
+	list := filepath.Join(t.TempDir(), "breached.txt")
+	os.WriteFile(list, []byte(strings.Join(lines, "\r\n")+"\r\n"), 0o600)
+	breached, err := OpenBreachedPasswords(list)
+	if err != nil {
+		t.Fatalf("OpenBreachedPasswords() error = %v", err)
+	}
+	for i := 0; i < 500; i++ {
+		if password := fmt.Sprintf("filler %d", i); !breached.Contains(password) {
+			t.Errorf("Contains(%q) = false, want true", password)
+		}
+	}
+	policy := DefaultPasswordPolicy
+	policy.Breached = breached
+
+	tests := []struct {
+		name     string
+		password string
+		wantErr  bool
+	}{
+		{
+			name:     "Acceptable password",
+			password: "correct horse battery staple",
+			wantErr:  false,
+		},
+		{
+			name:     "Too short",
+			password: "short",
+			wantErr:  true,
+		},
+		{
+			name:     "Short in bytes but long enough in characters",
+			password: "pässwörd",
+			wantErr:  false,
+		},
+		{
+			name:     "Longer than bcrypt allows",
+			password: strings.Repeat("a", MaxPasswordBytes+1),
+			wantErr:  true,
+		},
+		{
+			name:     "Breached password",
+			password: "password1",
+			wantErr:  true,
+		},
+		{
+			name:     "Breached password listed in lowercase",
+			password: "P@ssw0rd",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Validate(tt.password)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate(%q) error = %v, wantErr %v", tt.password, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOpenBreachedPasswordsRejectsGarbage(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{
+			name:    "Not a digest",
+			content: "not a digest\n",
+		},
+		{
+			name: "Not sorted",
+			content: "E38AD214943DAAD1D64C102FAEC29DE4AFE9DA3D:2427158\n" +
+				"21BD12DC183F740EE76F27B78EB39C8AD972A757:61\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := filepath.Join(t.TempDir(), "breached.txt")
+			os.WriteFile(list, []byte(tt.content), 0o600)
+			if _, err := OpenBreachedPasswords(list); err == nil {
+				t.Errorf("OpenBreachedPasswords() error = nil, want an error")
+			}
+		})
+	}
+}
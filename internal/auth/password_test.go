@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPasswordHashAlgorithms(t *testing.T) {
+	password := "correctPassword123!"
+	argonHash, err := HashPassword(password)
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+	bcryptHash, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	weakArgonHash := strings.Replace(argonHash, ",t=3,", ",t=1,", 1)
+
+	tests := []struct {
+		name       string
+		password   string
+		hash       string
+		wantErr    bool
+		wantRehash bool
+	}{
+		{
+			name:       "Argon2id hash",
+			password:   password,
+			hash:       argonHash,
+			wantErr:    false,
+			wantRehash: false,
+		},
+		{
+			name:       "Wrong password against Argon2id hash",
+			password:   "wrongPassword",
+			hash:       argonHash,
+			wantErr:    true,
+			wantRehash: false,
+		},
+		{
+			name:       "Legacy bcrypt hash",
+			password:   password,
+			hash:       string(bcryptHash),
+			wantErr:    false,
+			wantRehash: true,
+		},
+		{
+			name:       "Wrong password against bcrypt hash",
+			password:   "wrongPassword",
+			hash:       string(bcryptHash),
+			wantErr:    true,
+			wantRehash: true,
+		},
+		{
+			name:       "Argon2id hash with old parameters",
+			password:   password,
+			hash:       weakArgonHash,
+			wantErr:    true,
+			wantRehash: true,
+		},
+		{
+			name:       "Malformed Argon2id hash",
+			password:   password,
+			hash:       "$argon2id$v=19$m=65536$c2FsdA$a2V5",
+			wantErr:    true,
+			wantRehash: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckPasswordHash(tt.password, tt.hash)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckPasswordHash() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := NeedsRehash(tt.hash); got != tt.wantRehash {
+				t.Errorf("NeedsRehash() = %v, want %v", got, tt.wantRehash)
+			}
+		})
+	}
+}
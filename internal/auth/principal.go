@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	UserID        uuid.UUID
+	IsChirpyRed   bool
+	EmailVerified bool
+	Role          Role
+	// SessionID is the refresh token family an access JWT belongs to, and
+	// uuid.Nil for personal access tokens.
+	SessionID uuid.UUID
+	// Scopes is nil for access JWTs, which may do anything the user can,
+	// and lists what a personal access token was granted otherwise.
+	Scopes []string
+}
+
+func (p Principal) IsPersonalAccessToken() bool {
+	return p.Scopes != nil
+}
+
+// HasScope reports whether p may act with scope.
+func (p Principal) HasScope(scope string) bool {
+	if !p.IsPersonalAccessToken() {
+		return true
+	}
+	return scope != ScopeInteractive && slices.Contains(p.Scopes, scope)
+}
+
+// Can reports whether p's role grants perm. Personal access tokens never
+// carry a role's permissions; those routes need an interactive login.
+func (p Principal) Can(perm Permission) bool {
+	return !p.IsPersonalAccessToken() && p.Role.Can(perm)
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the caller stored by WithPrincipal, if any.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestPrincipalHasScope(t *testing.T) {
+	jwtUser := Principal{UserID: uuid.New()}
+	patUser := Principal{UserID: uuid.New(), Scopes: []string{ScopeChirpsRead}}
+
+	tests := []struct {
+		name      string
+		principal Principal
+		scope     string
+		want      bool
+	}{
+		{
+			name:      "JWT has every scope",
+			principal: jwtUser,
+			scope:     ScopeProfileWrite,
+			want:      true,
+		},
+		{
+			name:      "JWT may use interactive routes",
+			principal: jwtUser,
+			scope:     ScopeInteractive,
+			want:      true,
+		},
+		{
+			name:      "Token with the scope",
+			principal: patUser,
+			scope:     ScopeChirpsRead,
+			want:      true,
+		},
+		{
+			name:      "Token without the scope",
+			principal: patUser,
+			scope:     ScopeChirpsWrite,
+			want:      false,
+		},
+		{
+			name:      "Token on an interactive route",
+			principal: patUser,
+			scope:     ScopeInteractive,
+			want:      false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.principal.HasScope(tt.scope); got != tt.want {
+				t.Errorf("HasScope(%q) = %v, want %v", tt.scope, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrincipalFromContext(t *testing.T) {
+	want := Principal{UserID: uuid.New(), IsChirpyRed: true}
+
+	if _, ok := PrincipalFromContext(context.Background()); ok {
+		t.Errorf("PrincipalFromContext() found a principal in an empty context")
+	}
+
+	got, ok := PrincipalFromContext(WithPrincipal(context.Background(), want))
+	if !ok || got.UserID != want.UserID || got.IsChirpyRed != want.IsChirpyRed {
+		t.Errorf("PrincipalFromContext() = %+v, %v, want %+v, true", got, ok, want)
+	}
+}
@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"fmt"
+	"slices"
+)
+
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+// Permission is something a handler can check for instead of naming roles
+// directly, so what each role may do is decided in one place.
+type Permission string
+
+const (
+	PermissionModerate    Permission = "moderate"
+	PermissionViewMetrics Permission = "metrics:view"
+	PermissionResetData   Permission = "data:reset"
+	PermissionManageRoles Permission = "roles:manage"
+	PermissionUnlockUsers Permission = "users:unlock"
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleUser:      {},
+	RoleModerator: {PermissionModerate},
+	RoleAdmin: {
+		PermissionModerate,
+		PermissionViewMetrics,
+		PermissionResetData,
+		PermissionManageRoles,
+		PermissionUnlockUsers,
+	},
+}
+
+func ParseRole(s string) (Role, error) {
+	role := Role(s)
+	if _, ok := rolePermissions[role]; !ok {
+		return "", fmt.Errorf("unknown role %q", s)
+	}
+	return role, nil
+}
+
+func (r Role) Can(perm Permission) bool {
+	return slices.Contains(rolePermissions[r], perm)
+}
@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestParseRole(t *testing.T) {
+	tests := []struct {
+		name    string
+		role    string
+		want    Role
+		wantErr bool
+	}{
+		{
+			name:    "User",
+			role:    "user",
+			want:    RoleUser,
+			wantErr: false,
+		},
+		{
+			name:    "Admin",
+			role:    "admin",
+			want:    RoleAdmin,
+			wantErr: false,
+		},
+		{
+			name:    "Unknown role",
+			role:    "superuser",
+			wantErr: true,
+		},
+		{
+			name:    "Wrong case",
+			role:    "Admin",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRole(tt.role)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseRole() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParseRole() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrincipalCan(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal Principal
+		perm      Permission
+		want      bool
+	}{
+		{
+			name:      "User can't moderate",
+			principal: Principal{UserID: uuid.New(), Role: RoleUser},
+			perm:      PermissionModerate,
+			want:      false,
+		},
+		{
+			name:      "Moderator can moderate",
+			principal: Principal{UserID: uuid.New(), Role: RoleModerator},
+			perm:      PermissionModerate,
+			want:      true,
+		},
+		{
+			name:      "Moderator can't manage roles",
+			principal: Principal{UserID: uuid.New(), Role: RoleModerator},
+			perm:      PermissionManageRoles,
+			want:      false,
+		},
+		{
+			name:      "Admin can manage roles",
+			principal: Principal{UserID: uuid.New(), Role: RoleAdmin},
+			perm:      PermissionManageRoles,
+			want:      true,
+		},
+		{
+			name:      "Admin token can't use admin permissions",
+			principal: Principal{UserID: uuid.New(), Role: RoleAdmin, Scopes: []string{ScopeChirpsWrite}},
+			perm:      PermissionModerate,
+			want:      false,
+		},
+		{
+			name:      "Unknown role has no permissions",
+			principal: Principal{UserID: uuid.New(), Role: Role("")},
+			perm:      PermissionModerate,
+			want:      false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.principal.Can(tt.perm); got != tt.want {
+				t.Errorf("Can(%q) = %v, want %v", tt.perm, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	ScopeChirpsRead   = "chirps:read"
+	ScopeChirpsWrite  = "chirps:write"
+	ScopeProfileWrite = "profile:write"
+	// ScopeInteractive marks routes that need a real login, such as
+	// managing sessions and tokens. No personal access token has it.
+	ScopeInteractive = ""
+)
+
+// Scopes lists every scope a personal access token can be granted.
+var Scopes = []string{ScopeChirpsRead, ScopeChirpsWrite, ScopeProfileWrite}
+
+// PersonalAccessTokenPrefix tells personal access tokens apart from JWTs
+// and makes leaked tokens easy to spot.
+const PersonalAccessTokenPrefix = "chirpy_pat_"
+
+// ValidateScopes checks that scopes is a non-empty list of known scopes
+// and returns it sorted without duplicates.
+func ValidateScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("at least one scope is required, one of %s", strings.Join(Scopes, ", "))
+	}
+	out := []string{}
+	for _, scope := range scopes {
+		if !slices.Contains(Scopes, scope) {
+			return nil, fmt.Errorf("unknown scope %q", scope)
+		}
+		if !slices.Contains(out, scope) {
+			out = append(out, scope)
+		}
+	}
+	slices.Sort(out)
+	return out, nil
+}
+
+func MakePersonalAccessToken() string {
+	return PersonalAccessTokenPrefix + MakeRefreshToken()
+}
+
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, PersonalAccessTokenPrefix)
+}
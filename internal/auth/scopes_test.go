@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestValidateScopes(t *testing.T) {
+	tests := []struct {
+		name    string
+		scopes  []string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "Known scopes",
+			scopes:  []string{ScopeChirpsWrite, ScopeChirpsRead},
+			want:    []string{ScopeChirpsRead, ScopeChirpsWrite},
+			wantErr: false,
+		},
+		{
+			name:    "Duplicates are dropped",
+			scopes:  []string{ScopeProfileWrite, ScopeProfileWrite},
+			want:    []string{ScopeProfileWrite},
+			wantErr: false,
+		},
+		{
+			name:    "No scopes",
+			scopes:  []string{},
+			wantErr: true,
+		},
+		{
+			name:    "Unknown scope",
+			scopes:  []string{ScopeChirpsRead, "admin"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateScopes(tt.scopes)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateScopes() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !slices.Equal(got, tt.want) {
+				t.Errorf("ValidateScopes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package auth
+
+import "time"
+
+// LoginThrottle decides how long a client has to wait after failed logins.
+// Each failure doubles the wait, starting at BaseDelay and capped at
+// MaxDelay, until Threshold failures lock the key out for Lockout.
+type LoginThrottle struct {
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	Threshold int
+	Lockout   time.Duration
+}
+
+// RetryAfter returns how long to wait after the given number of consecutive
+// failures.
+func (t LoginThrottle) RetryAfter(failures int) time.Duration {
+	if failures <= 0 {
+		return 0
+	}
+	if failures >= t.Threshold {
+		return t.Lockout
+	}
+	delay := t.BaseDelay
+	for i := 1; i < failures && delay < t.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, t.MaxDelay)
+}
+
+// Locks reports whether the given number of failures locks the key out.
+func (t LoginThrottle) Locks(failures int) bool {
+	return failures >= t.Threshold
+}
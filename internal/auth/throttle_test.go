@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoginThrottleRetryAfter(t *testing.T) {
+	throttle := LoginThrottle{
+		BaseDelay: time.Second,
+		MaxDelay:  10 * time.Second,
+		Threshold: 10,
+		Lockout:   15 * time.Minute,
+	}
+
+	tests := []struct {
+		name     string
+		failures int
+		want     time.Duration
+	}{
+		{
+			name:     "No failures",
+			failures: 0,
+			want:     0,
+		},
+		{
+			name:     "First failure",
+			failures: 1,
+			want:     time.Second,
+		},
+		{
+			name:     "Third failure",
+			failures: 3,
+			want:     4 * time.Second,
+		},
+		{
+			name:     "Capped at the maximum delay",
+			failures: 9,
+			want:     10 * time.Second,
+		},
+		{
+			name:     "Locked out at the threshold",
+			failures: 10,
+			want:     15 * time.Minute,
+		},
+		{
+			name:     "Still locked out past the threshold",
+			failures: 25,
+			want:     15 * time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := throttle.RetryAfter(tt.failures); got != tt.want {
+				t.Errorf("RetryAfter(%d) = %v, want %v", tt.failures, got, tt.want)
+			}
+			if got, want := throttle.Locks(tt.failures), tt.failures >= 10; got != want {
+				t.Errorf("Locks(%d) = %v, want %v", tt.failures, got, want)
+			}
+		})
+	}
+}
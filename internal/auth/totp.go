@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP parameters every authenticator app understands.
+const (
+	TOTPPeriod = 30 * time.Second
+	TOTPDigits = 6
+	// totpSkew is how many periods either side of now are accepted, to
+	// allow for clocks that drift and codes typed near a period boundary.
+	totpSkew = 1
+)
+
+var (
+	ErrInvalidTOTPCode = errors.New("invalid TOTP code")
+	ErrTOTPCodeReused  = errors.New("TOTP code has already been used")
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// MakeTOTPSecret returns a random 160-bit secret, base32 encoded as
+// authenticator apps expect it.
+func MakeTOTPSecret() string {
+	key := make([]byte, 20)
+	rand.Read(key)
+	return totpEncoding.EncodeToString(key)
+}
+
+// TOTPURI is the otpauth:// URI apps read from a QR code.
+func TOTPURI(secret, account, issuer string) string {
+	v := url.Values{}
+	v.Set("secret", secret)
+	v.Set("issuer", issuer)
+	v.Set("algorithm", "SHA1")
+	v.Set("digits", fmt.Sprint(TOTPDigits))
+	v.Set("period", fmt.Sprint(int(TOTPPeriod.Seconds())))
+	label := url.PathEscape(issuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + v.Encode()
+}
+
+// ValidateTOTP checks code against secret at time now. lastStep is the time
+// step of the last code accepted for this secret; codes from that step or
+// before are refused so an observed code can't be replayed. The step of the
+// accepted code is returned to be stored as the new lastStep.
+func ValidateTOTP(secret, code string, now time.Time, lastStep int64) (int64, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return 0, fmt.Errorf("malformed TOTP secret: %w", err)
+	}
+	code = strings.TrimSpace(code)
+
+	current := totpStep(now, TOTPPeriod)
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		want := hotp(key, uint64(step), TOTPDigits, sha1.New)
+		if subtle.ConstantTimeCompare([]byte(want), []byte(code)) != 1 {
+			continue
+		}
+		if step <= lastStep {
+			return 0, ErrTOTPCodeReused
+		}
+		return step, nil
+	}
+	return 0, ErrInvalidTOTPCode
+}
+
+func totpStep(t time.Time, period time.Duration) int64 {
+	return t.Unix() / int64(period.Seconds())
+}
+
+// hotp is the RFC 4226 HMAC-based one-time password for counter.
+func hotp(key []byte, counter uint64, digits int, h func() hash.Hash) string {
+	mac := hmac.New(h, key)
+	binary.Write(mac, binary.BigEndian, counter)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, value%mod)
+}
+
+// recoveryCodeEncoding leaves out letters that are easy to mix up with
+// digits when codes are copied by hand.
+var recoveryCodeEncoding = base32.NewEncoding("ABCDEFGHJKLMNPQRSTUVWXYZ23456789").WithPadding(base32.NoPadding)
+
+// MakeRecoveryCodes returns n one-time codes for signing in without the
+// authenticator, formatted as XXXX-XXXX-XXXX-XXXX. Store them with
+// HashRecoveryCode.
+func MakeRecoveryCodes(n int) []string {
+	codes := make([]string, n)
+	for i := range codes {
+		key := make([]byte, 10)
+		rand.Read(key)
+		s := recoveryCodeEncoding.EncodeToString(key)
+		codes[i] = s[0:4] + "-" + s[4:8] + "-" + s[8:12] + "-" + s[12:16]
+	}
+	return codes
+}
+
+// HashRecoveryCode digests a recovery code the way it was typed, ignoring
+// case, spaces and dashes.
+func HashRecoveryCode(code string) string {
+	code = strings.ToUpper(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	return HashToken(code)
+}
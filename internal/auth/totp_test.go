@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"errors"
+	"hash"
+	"testing"
+	"time"
+)
+
+// The test vectors of RFC 6238, Appendix B.
+func TestTOTPVectors(t *testing.T) {
+	seed := "1234567890"
+	keys := map[string]struct {
+		key []byte
+		h   func() hash.Hash
+	}{
+		"SHA1":   {[]byte(seed + seed), sha1.New},
+		"SHA256": {[]byte(seed + seed + seed + "12"), sha256.New},
+		"SHA512": {[]byte(seed + seed + seed + seed + seed + seed + "1234"), sha512.New},
+	}
+
+	tests := []struct {
+		unix int64
+		alg  string
+		want string
+	}{
+		{unix: 59, alg: "SHA1", want: "94287082"},
+		{unix: 59, alg: "SHA256", want: "46119246"},
+		{unix: 59, alg: "SHA512", want: "90693936"},
+		{unix: 1111111109, alg: "SHA1", want: "07081804"},
+		{unix: 1111111109, alg: "SHA256", want: "68084774"},
+		{unix: 1111111109, alg: "SHA512", want: "25091201"},
+		{unix: 1111111111, alg: "SHA1", want: "14050471"},
+		{unix: 1111111111, alg: "SHA256", want: "67062674"},
+		{unix: 1111111111, alg: "SHA512", want: "99943326"},
+		{unix: 1234567890, alg: "SHA1", want: "89005924"},
+		{unix: 1234567890, alg: "SHA256", want: "91819424"},
+		{unix: 1234567890, alg: "SHA512", want: "93441116"},
+		{unix: 2000000000, alg: "SHA1", want: "69279037"},
+		{unix: 2000000000, alg: "SHA256", want: "90698825"},
+		{unix: 2000000000, alg: "SHA512", want: "38618901"},
+		{unix: 20000000000, alg: "SHA1", want: "65353130"},
+		{unix: 20000000000, alg: "SHA256", want: "77737706"},
+		{unix: 20000000000, alg: "SHA512", want: "47863826"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.alg+"/"+time.Unix(tt.unix, 0).UTC().Format(time.RFC3339), func(t *testing.T) {
+			k := keys[tt.alg]
+			step := totpStep(time.Unix(tt.unix, 0), TOTPPeriod)
+			if got := hotp(k.key, uint64(step), 8, k.h); got != tt.want {
+				t.Errorf("hotp() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	key := []byte("12345678901234567890")
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(key)
+	now := time.Unix(1111111111, 0)
+	step := totpStep(now, TOTPPeriod)
+	codeAt := func(step int64) string {
+		return hotp(key, uint64(step), TOTPDigits, sha1.New)
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		lastStep int64
+		wantStep int64
+		wantErr  error
+	}{
+		{
+			name:     "Current code",
+			code:     codeAt(step),
+			wantStep: step,
+		},
+		{
+			name:     "Code from the previous period",
+			code:     codeAt(step - 1),
+			wantStep: step - 1,
+		},
+		{
+			name:    "Code from too long ago",
+			code:    codeAt(step - 2),
+			wantErr: ErrInvalidTOTPCode,
+		},
+		{
+			name:     "Replayed code",
+			code:     codeAt(step),
+			lastStep: step,
+			wantErr:  ErrTOTPCodeReused,
+		},
+		{
+			name:     "Earlier code after a newer one was used",
+			code:     codeAt(step - 1),
+			lastStep: step,
+			wantErr:  ErrTOTPCodeReused,
+		},
+		{
+			name:    "Wrong code",
+			code:    "000000",
+			wantErr: ErrInvalidTOTPCode,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, err := ValidateTOTP(secret, tt.code, now, tt.lastStep)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ValidateTOTP() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if gotStep != tt.wantStep {
+				t.Errorf("ValidateTOTP() gotStep = %d, want %d", gotStep, tt.wantStep)
+			}
+		})
+	}
+}
+
+func TestHashRecoveryCode(t *testing.T) {
+	codes := MakeRecoveryCodes(2)
+	if codes[0] == codes[1] {
+		t.Fatalf("MakeRecoveryCodes() returned the same code twice: %v", codes)
+	}
+
+	typed := " " + codes[0][0:4] + codes[0][5:9] + "-" + codes[0][10:] + " "
+	if HashRecoveryCode(typed) != HashRecoveryCode(codes[0]) {
+		t.Errorf("HashRecoveryCode(%q) differs from HashRecoveryCode(%q)", typed, codes[0])
+	}
+	if HashRecoveryCode(codes[1]) == HashRecoveryCode(codes[0]) {
+		t.Errorf("different recovery codes have the same hash")
+	}
+}
@@ -13,46 +13,120 @@ import (
 	"github.com/google/uuid"
 )
 
-const getUserIdFromRefreshToken = `-- name: GetUserIdFromRefreshToken :one
-SELECT user_id, revoked_at 
+const deleteStaleRefreshTokens = `-- name: DeleteStaleRefreshTokens :execrows
+DELETE FROM refresh_tokens
+WHERE expires_at <= NOW()
+   OR (revoked_at < $1 AND rotated_at IS NULL)
+`
+
+func (q *Queries) DeleteStaleRefreshTokens(ctx context.Context, revokedBefore sql.NullTime) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteStaleRefreshTokens, revokedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getRefreshTokenFamily = `-- name: GetRefreshTokenFamily :one
+SELECT user_id, family_id, expires_at, revoked_at, rotated_at
 FROM refresh_tokens
-WHERE token = $1
+WHERE token_hash = $1
 `
 
-type GetUserIdFromRefreshTokenRow struct {
+type GetRefreshTokenFamilyRow struct {
 	UserID    uuid.UUID
+	FamilyID  uuid.UUID
+	ExpiresAt time.Time
 	RevokedAt sql.NullTime
+	RotatedAt sql.NullTime
 }
 
-func (q *Queries) GetUserIdFromRefreshToken(ctx context.Context, token string) (GetUserIdFromRefreshTokenRow, error) {
-	row := q.db.QueryRowContext(ctx, getUserIdFromRefreshToken, token)
-	var i GetUserIdFromRefreshTokenRow
-	err := row.Scan(&i.UserID, &i.RevokedAt)
+func (q *Queries) GetRefreshTokenFamily(ctx context.Context, tokenHash string) (GetRefreshTokenFamilyRow, error) {
+	row := q.db.QueryRowContext(ctx, getRefreshTokenFamily, tokenHash)
+	var i GetRefreshTokenFamilyRow
+	err := row.Scan(
+		&i.UserID,
+		&i.FamilyID,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.RotatedAt,
+	)
 	return i, err
 }
 
+const revokeRefreshTokenFamily = `-- name: RevokeRefreshTokenFamily :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE family_id = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeRefreshTokenFamily, familyID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const revokeToken = `-- name: RevokeToken :execresult
 UPDATE refresh_tokens 
 SET revoked_at = NOW(),
     updated_at = NOW()
-WHERE token = $1
+WHERE token_hash = $1
 `
 
-func (q *Queries) RevokeToken(ctx context.Context, token string) (sql.Result, error) {
-	return q.db.ExecContext(ctx, revokeToken, token)
+func (q *Queries) RevokeToken(ctx context.Context, tokenHash string) (sql.Result, error) {
+	return q.db.ExecContext(ctx, revokeToken, tokenHash)
+}
+
+const rotateRefreshToken = `-- name: RotateRefreshToken :one
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    rotated_at = NOW(),
+    updated_at = NOW()
+WHERE token_hash = $1 AND revoked_at IS NULL AND expires_at > NOW()
+
+RETURNING user_id, family_id, signed_in_at
+`
+
+type RotateRefreshTokenRow struct {
+	UserID     uuid.UUID
+	FamilyID   uuid.UUID
+	SignedInAt time.Time
+}
+
+func (q *Queries) RotateRefreshToken(ctx context.Context, tokenHash string) (RotateRefreshTokenRow, error) {
+	row := q.db.QueryRowContext(ctx, rotateRefreshToken, tokenHash)
+	var i RotateRefreshTokenRow
+	err := row.Scan(&i.UserID, &i.FamilyID, &i.SignedInAt)
+	return i, err
 }
 
 const saveRefreshToken = `-- name: SaveRefreshToken :execresult
-INSERT INTO refresh_tokens(token, created_at, updated_at, user_id, expires_at, revoked_at)
-VALUES ($1, NOW(), NOW(), $2, $3, NULL)
+INSERT INTO refresh_tokens(token_hash, created_at, updated_at, user_id, expires_at, revoked_at, family_id,
+                           user_agent, ip_address, signed_in_at, last_used_at)
+VALUES ($1, NOW(), NOW(), $2, $3, NULL, $4, $5, $6, $7, NOW())
 `
 
 type SaveRefreshTokenParams struct {
-	Token     string
-	UserID    uuid.UUID
-	ExpiresAt time.Time
+	TokenHash  string
+	UserID     uuid.UUID
+	ExpiresAt  time.Time
+	FamilyID   uuid.UUID
+	UserAgent  string
+	IpAddress  string
+	SignedInAt time.Time
 }
 
 func (q *Queries) SaveRefreshToken(ctx context.Context, arg SaveRefreshTokenParams) (sql.Result, error) {
-	return q.db.ExecContext(ctx, saveRefreshToken, arg.Token, arg.UserID, arg.ExpiresAt)
+	return q.db.ExecContext(ctx, saveRefreshToken,
+		arg.TokenHash,
+		arg.UserID,
+		arg.ExpiresAt,
+		arg.FamilyID,
+		arg.UserAgent,
+		arg.IpAddress,
+		arg.SignedInAt,
+	)
 }
@@ -7,11 +7,39 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const bumpUserTokenVersion = `-- name: BumpUserTokenVersion :exec
+UPDATE users
+SET token_version = token_version + 1,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) BumpUserTokenVersion(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, bumpUserTokenVersion, id)
+	return err
+}
+
+const cancelUserDeletion = `-- name: CancelUserDeletion :execrows
+UPDATE users
+SET delete_after = NULL,
+    updated_at = NOW()
+WHERE id = $1 AND delete_after IS NOT NULL
+`
+
+func (q *Queries) CancelUserDeletion(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, cancelUserDeletion, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (id, created_at, updated_at, email, hashed_password)
 VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2)
@@ -53,8 +81,46 @@ func (q *Queries) DeleteAllUsers(ctx context.Context) error {
 	return err
 }
 
+const deleteUser = `-- name: DeleteUser :execrows
+DELETE FROM users
+WHERE id = $1 AND delete_after <= NOW()
+`
+
+func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getUserAuthState = `-- name: GetUserAuthState :one
+SELECT token_version, is_chirpy_red, role, email_verified_at IS NOT NULL AS email_verified
+FROM users
+WHERE id = $1
+`
+
+type GetUserAuthStateRow struct {
+	TokenVersion  int32
+	IsChirpyRed   bool
+	Role          string
+	EmailVerified bool
+}
+
+func (q *Queries) GetUserAuthState(ctx context.Context, id uuid.UUID) (GetUserAuthStateRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserAuthState, id)
+	var i GetUserAuthStateRow
+	err := row.Scan(
+		&i.TokenVersion,
+		&i.IsChirpyRed,
+		&i.Role,
+		&i.EmailVerified,
+	)
+	return i, err
+}
+
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, token_version, role, email_verified_at, pending_email, delete_after
 FROM users
 WHERE email = $1
 `
@@ -69,26 +135,132 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.TokenVersion,
+		&i.Role,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.DeleteAfter,
 	)
 	return i, err
 }
 
-const updateUserEmailAndPassword = `-- name: UpdateUserEmailAndPassword :exec
+const getUserById = `-- name: GetUserById :one
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, token_version, role, email_verified_at, pending_email, delete_after
+FROM users
+WHERE id = $1
+`
+
+func (q *Queries) GetUserById(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserById, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.TokenVersion,
+		&i.Role,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.DeleteAfter,
+	)
+	return i, err
+}
+
+const getUserTokenVersion = `-- name: GetUserTokenVersion :one
+SELECT token_version
+FROM users
+WHERE id = $1
+`
+
+func (q *Queries) GetUserTokenVersion(ctx context.Context, id uuid.UUID) (int32, error) {
+	row := q.db.QueryRowContext(ctx, getUserTokenVersion, id)
+	var token_version int32
+	err := row.Scan(&token_version)
+	return token_version, err
+}
+
+const listUsersDueForDeletion = `-- name: ListUsersDueForDeletion :many
+SELECT id
+FROM users
+WHERE delete_after <= NOW()
+ORDER BY delete_after
+LIMIT $1
+`
+
+func (q *Queries) ListUsersDueForDeletion(ctx context.Context, limit int32) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, listUsersDueForDeletion, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const scheduleUserDeletion = `-- name: ScheduleUserDeletion :exec
 UPDATE users
-SET email = $2,
-    hashed_password = $3,
+SET delete_after = $2,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type ScheduleUserDeletionParams struct {
+	ID          uuid.UUID
+	DeleteAfter time.Time
+}
+
+func (q *Queries) ScheduleUserDeletion(ctx context.Context, arg ScheduleUserDeletionParams) error {
+	_, err := q.db.ExecContext(ctx, scheduleUserDeletion, arg.ID, arg.DeleteAfter)
+	return err
+}
+
+const setUserPendingEmail = `-- name: SetUserPendingEmail :exec
+UPDATE users
+SET pending_email = $2,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type SetUserPendingEmailParams struct {
+	ID           uuid.UUID
+	PendingEmail sql.NullString
+}
+
+func (q *Queries) SetUserPendingEmail(ctx context.Context, arg SetUserPendingEmailParams) error {
+	_, err := q.db.ExecContext(ctx, setUserPendingEmail, arg.ID, arg.PendingEmail)
+	return err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users
+SET hashed_password = $2,
     updated_at = NOW()
 WHERE id = $1
 `
 
-type UpdateUserEmailAndPasswordParams struct {
+type UpdateUserPasswordParams struct {
 	ID             uuid.UUID
-	Email          string
 	HashedPassword string
 }
 
-func (q *Queries) UpdateUserEmailAndPassword(ctx context.Context, arg UpdateUserEmailAndPasswordParams) error {
-	_, err := q.db.ExecContext(ctx, updateUserEmailAndPassword, arg.ID, arg.Email, arg.HashedPassword)
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.ID, arg.HashedPassword)
 	return err
 }
 
@@ -103,3 +275,27 @@ func (q *Queries) UpgradeUser(ctx context.Context, id uuid.UUID) error {
 	_, err := q.db.ExecContext(ctx, upgradeUser, id)
 	return err
 }
+
+const verifyUserEmail = `-- name: VerifyUserEmail :execrows
+-- Confirming the current address keeps a pending change to another one.
+UPDATE users
+SET email = $2,
+    pending_email = CASE WHEN pending_email = $2 THEN NULL ELSE pending_email END,
+    email_verified_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+AND (email = $2 OR pending_email = $2)
+`
+
+type VerifyUserEmailParams struct {
+	ID    uuid.UUID
+	Email string
+}
+
+func (q *Queries) VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, verifyUserEmail, arg.ID, arg.Email)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
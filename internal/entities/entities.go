@@ -0,0 +1,90 @@
+package entities
+
+import (
+	"strings"
+	"unicode"
+)
+
+type Type string
+
+const (
+	TypeHashtag Type = "hashtag"
+	TypeMention Type = "mention"
+)
+
+// Entity is a #tag or @handle found in a chirp body. Start and End are
+// offsets in characters (runes), End being exclusive, and cover the
+// leading '#' or '@'. Text holds the tag or handle without it.
+type Entity struct {
+	Type  Type   `json:"type"`
+	Text  string `json:"text"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+func Parse(body string) []Entity {
+	found := []Entity{}
+	runes := []rune(body)
+	for i := 0; i < len(runes); i++ {
+		var entityType Type
+		switch runes[i] {
+		case '#':
+			entityType = TypeHashtag
+		case '@':
+			entityType = TypeMention
+		default:
+			continue
+		}
+
+		// "a@b" or "a#b" is part of a word (an email, a URL fragment),
+		// not the start of an entity.
+		if i > 0 && isWordRune(runes[i-1]) {
+			continue
+		}
+
+		end := i + 1
+		for end < len(runes) && isWordRune(runes[end]) {
+			end++
+		}
+		if end == i+1 {
+			continue
+		}
+
+		found = append(found, Entity{
+			Type:  entityType,
+			Text:  string(runes[i+1 : end]),
+			Start: i,
+			End:   end,
+		})
+		i = end - 1
+	}
+	return found
+}
+
+// Normalize returns the form tags and handles are indexed under.
+func Normalize(text string) string {
+	return strings.ToLower(strings.TrimLeft(text, "#@"))
+}
+
+// Values returns the distinct normalized texts of entities of the given
+// type, in order of first appearance.
+func Values(found []Entity, entityType Type) []string {
+	seen := map[string]bool{}
+	values := []string{}
+	for _, e := range found {
+		if e.Type != entityType {
+			continue
+		}
+		v := Normalize(e.Text)
+		if seen[v] {
+			continue
+		}
+		seen[v] = true
+		values = append(values, v)
+	}
+	return values
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,84 @@
+package entities
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []Entity
+	}{
+		{
+			name: "No entities",
+			body: "just a plain chirp",
+			want: []Entity{},
+		},
+		{
+			name: "Hashtag and mention",
+			body: "hi @Bob, see #golang!",
+			want: []Entity{
+				{Type: TypeMention, Text: "Bob", Start: 3, End: 7},
+				{Type: TypeHashtag, Text: "golang", Start: 13, End: 20},
+			},
+		},
+		{
+			name: "Email is not a mention",
+			body: "mail me at bob@example.com",
+			want: []Entity{},
+		},
+		{
+			name: "Lone markers are ignored",
+			body: "# @ #!",
+			want: []Entity{},
+		},
+		{
+			name: "Offsets count characters not bytes",
+			body: "café #été",
+			want: []Entity{
+				{Type: TypeHashtag, Text: "été", Start: 5, End: 9},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Parse(tt.body)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValues(t *testing.T) {
+	found := Parse("#Go #go @ann #rust @Ann")
+
+	tests := []struct {
+		name       string
+		entityType Type
+		want       []string
+	}{
+		{
+			name:       "Hashtags are deduplicated case-insensitively",
+			entityType: TypeHashtag,
+			want:       []string{"go", "rust"},
+		},
+		{
+			name:       "Mentions are deduplicated case-insensitively",
+			entityType: TypeMention,
+			want:       []string{"ann"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Values(found, tt.entityType)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Values() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
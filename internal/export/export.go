@@ -0,0 +1,39 @@
+package export
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"io"
+)
+
+// Store keeps finished archives until they are downloaded or expire. Keys
+// are chosen by the caller and must be plain file names.
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// File is one document of an archive. Data is written as indented JSON.
+type File struct {
+	Name string
+	Data any
+}
+
+// WriteZip writes files, in order, as JSON documents of a ZIP archive.
+func WriteZip(w io.Writer, files []File) error {
+	zw := zip.NewWriter(w)
+	for _, f := range files {
+		fw, err := zw.Create(f.Name)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.Data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
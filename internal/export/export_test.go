@@ -0,0 +1,51 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+)
+
+func TestWriteZip(t *testing.T) {
+	type profile struct {
+		Email string `json:"email"`
+	}
+	files := []File{
+		{Name: "profile.json", Data: profile{Email: "walt@breakingbad.com"}},
+		{Name: "chirps.json", Data: []string{}},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteZip(&buf, files); err != nil {
+		t.Fatalf("WriteZip() error = %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("zip.NewReader() error = %v", err)
+	}
+	if len(zr.File) != len(files) {
+		t.Fatalf("archive has %d files, want %d", len(zr.File), len(files))
+	}
+	for i, f := range zr.File {
+		if f.Name != files[i].Name {
+			t.Errorf("file %d is %q, want %q", i, f.Name, files[i].Name)
+		}
+	}
+
+	rc, err := zr.File[0].Open()
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer rc.Close()
+	data, _ := io.ReadAll(rc)
+	got := profile{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("profile.json is not JSON: %v", err)
+	}
+	if got.Email != "walt@breakingbad.com" {
+		t.Errorf("Email = %q, want walt@breakingbad.com", got.Email)
+	}
+}
@@ -0,0 +1,96 @@
+package export
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestSignerVerify(t *testing.T) {
+	signer, err := NewSigner([]byte(strings.Repeat("k", 32)))
+	if err != nil {
+		t.Fatalf("NewSigner() error = %v", err)
+	}
+	other, _ := NewSigner([]byte(strings.Repeat("o", 32)))
+
+	id := uuid.New()
+	now := time.Now()
+	expires := now.Add(15 * time.Minute)
+	signature := signer.Sign(id, expires)
+
+	tests := []struct {
+		name      string
+		id        uuid.UUID
+		expires   int64
+		signature string
+		now       time.Time
+		wantErr   error
+	}{
+		{
+			name:      "Valid link",
+			id:        id,
+			expires:   expires.Unix(),
+			signature: signature,
+			now:       now,
+			wantErr:   nil,
+		},
+		{
+			name:      "Expired link",
+			id:        id,
+			expires:   expires.Unix(),
+			signature: signature,
+			now:       expires.Add(time.Second),
+			wantErr:   ErrLinkExpired,
+		},
+		{
+			name:      "Extended expiry",
+			id:        id,
+			expires:   expires.Add(time.Hour).Unix(),
+			signature: signature,
+			now:       now,
+			wantErr:   ErrInvalidSignature,
+		},
+		{
+			name:      "Another export",
+			id:        uuid.New(),
+			expires:   expires.Unix(),
+			signature: signature,
+			now:       now,
+			wantErr:   ErrInvalidSignature,
+		},
+		{
+			name:      "Signed with another key",
+			id:        id,
+			expires:   expires.Unix(),
+			signature: other.Sign(id, expires),
+			now:       now,
+			wantErr:   ErrInvalidSignature,
+		},
+		{
+			name:      "Signature not hex",
+			id:        id,
+			expires:   expires.Unix(),
+			signature: "not-hex",
+			now:       now,
+			wantErr:   ErrInvalidSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := signer.Verify(tt.id, tt.expires, tt.signature, tt.now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewSignerShortKey(t *testing.T) {
+	if _, err := NewSigner([]byte("too short")); err == nil {
+		t.Error("NewSigner() error = nil, want an error for a short key")
+	}
+}
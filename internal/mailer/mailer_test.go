@@ -0,0 +1,94 @@
+package mailer
+
+import (
+	"context"
+	"net/mail"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFileOutboxSend(t *testing.T) {
+	tests := []struct {
+		name    string
+		msg     Message
+		wantErr bool
+	}{
+		{
+			name: "Plain message",
+			msg: Message{
+				To:      "walt@breakingbad.com",
+				Subject: "Reset your Chirpy password",
+				Body:    "Line one\nLine two\n",
+			},
+			wantErr: false,
+		},
+		{
+			name: "Recipient with extra headers",
+			msg: Message{
+				To:      "walt@breakingbad.com\r\nBcc: jesse@breakingbad.com",
+				Subject: "Hello",
+			},
+			wantErr: true,
+		},
+		{
+			name: "Subject with extra headers",
+			msg: Message{
+				To:      "walt@breakingbad.com",
+				Subject: "Hello\r\nBcc: jesse@breakingbad.com",
+			},
+			wantErr: true,
+		},
+		{
+			name: "Two recipients",
+			msg: Message{
+				To:      "walt@breakingbad.com, jesse@breakingbad.com",
+				Subject: "Hello",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outbox, err := NewFileOutbox(t.TempDir(), "Chirpy <no-reply@chirpy.test>")
+			if err != nil {
+				t.Fatalf("NewFileOutbox() error = %v", err)
+			}
+
+			err = outbox.Send(context.Background(), tt.msg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			files, err := outbox.Messages()
+			if err != nil {
+				t.Fatalf("Messages() error = %v", err)
+			}
+			if tt.wantErr {
+				if len(files) != 0 {
+					t.Errorf("Messages() = %v, want none after a failed send", files)
+				}
+				return
+			}
+			if len(files) != 1 {
+				t.Fatalf("Messages() = %v, want one message", files)
+			}
+
+			data, _ := os.ReadFile(files[0])
+			parsed, err := mail.ReadMessage(strings.NewReader(string(data)))
+			if err != nil {
+				t.Fatalf("ReadMessage() error = %v", err)
+			}
+			if got := parsed.Header.Get("To"); got != "<walt@breakingbad.com>" {
+				t.Errorf("To = %q, want <walt@breakingbad.com>", got)
+			}
+			if got := parsed.Header.Get("Subject"); got != tt.msg.Subject {
+				t.Errorf("Subject = %q, want %q", got, tt.msg.Subject)
+			}
+			if !strings.Contains(string(data), "Line one\r\nLine two\r\n") {
+				t.Errorf("body lines are not CRLF terminated: %q", data)
+			}
+		})
+	}
+}
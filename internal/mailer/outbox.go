@@ -0,0 +1,47 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileOutbox writes each message to a .eml file in a directory instead of
+// sending it, for development and tests.
+type FileOutbox struct {
+	dir  string
+	from string
+}
+
+func NewFileOutbox(dir, from string) (*FileOutbox, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileOutbox{dir: dir, from: from}, nil
+}
+
+func (o *FileOutbox) Send(ctx context.Context, msg Message) error {
+	now := time.Now()
+	data, err := format(o.from, msg, now)
+	if err != nil {
+		return err
+	}
+
+	// The timestamp prefix keeps the files in the order they were sent.
+	f, err := os.CreateTemp(o.dir, now.UTC().Format("20060102T150405.000000000")+"-*.eml")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	return f.Close()
+}
+
+// Messages returns the paths of the messages in the outbox, oldest first.
+func (o *FileOutbox) Messages() ([]string, error) {
+	return filepath.Glob(filepath.Join(o.dir, "*.eml"))
+}
@@ -0,0 +1,85 @@
+package mailer
+
+import (
+	"context"
+	"sync"
+)
+
+// Job prepares and sends one message. It should give up when ctx is
+// cancelled.
+type Job func(ctx context.Context)
+
+// Queue runs Jobs on a fixed number of workers, so that requests asking for
+// mail can't start a goroutine each. Jobs that don't fit are dropped.
+type Queue struct {
+	jobs   chan Job
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewQueue starts workers that take jobs from a queue holding up to size
+// of them.
+func NewQueue(workers, size int) *Queue {
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &Queue{
+		jobs:   make(chan Job, size),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	q.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go q.work()
+	}
+	return q
+}
+
+// Enqueue adds job to the queue, and reports false if the queue is full or
+// stopped.
+func (q *Queue) Enqueue(job Job) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return false
+	}
+	select {
+	case q.jobs <- job:
+		return true
+	default:
+		return false
+	}
+}
+
+// Stop takes no more jobs and waits for the queued ones to finish, or for
+// ctx to expire, in which case the jobs still running are cancelled.
+func (q *Queue) Stop(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.jobs)
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		q.cancel()
+		return ctx.Err()
+	}
+}
+
+func (q *Queue) work() {
+	defer q.wg.Done()
+	for job := range q.jobs {
+		job(q.ctx)
+	}
+}
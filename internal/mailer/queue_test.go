@@ -0,0 +1,70 @@
+package mailer
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestQueueRunsJobs(t *testing.T) {
+	q := NewQueue(2, 10)
+	runs := atomic.Int32{}
+	for i := 0; i < 5; i++ {
+		if !q.Enqueue(func(ctx context.Context) { runs.Add(1) }) {
+			t.Fatalf("Enqueue() = false, want true")
+		}
+	}
+
+	if err := q.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if got := runs.Load(); got != 5 {
+		t.Errorf("ran %d jobs, want 5", got)
+	}
+	if q.Enqueue(func(ctx context.Context) {}) {
+		t.Errorf("Enqueue() after Stop() = true, want false")
+	}
+	if err := q.Stop(context.Background()); err != nil {
+		t.Errorf("second Stop() error = %v", err)
+	}
+}
+
+func TestQueueDropsWhenFull(t *testing.T) {
+	q := NewQueue(1, 1)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	q.Enqueue(func(ctx context.Context) {
+		close(started)
+		<-release
+	})
+	<-started
+
+	if !q.Enqueue(func(ctx context.Context) {}) {
+		t.Fatalf("Enqueue() into a free slot = false, want true")
+	}
+	if q.Enqueue(func(ctx context.Context) {}) {
+		t.Errorf("Enqueue() into a full queue = true, want false")
+	}
+
+	close(release)
+	if err := q.Stop(context.Background()); err != nil {
+		t.Errorf("Stop() error = %v", err)
+	}
+}
+
+func TestQueueStopCancelsJobs(t *testing.T) {
+	q := NewQueue(1, 1)
+	started := make(chan struct{})
+	q.Enqueue(func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+	})
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := q.Stop(ctx); err == nil {
+		t.Errorf("Stop() error = nil, want the context's error")
+	}
+}
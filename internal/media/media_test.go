@@ -0,0 +1,118 @@
+package media
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalStoreKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		wantErr bool
+	}{
+		{
+			name:    "Plain file name",
+			key:     "3f2b.png",
+			wantErr: false,
+		},
+		{
+			name:    "Empty key",
+			key:     "",
+			wantErr: true,
+		},
+		{
+			name:    "Parent directory",
+			key:     "..",
+			wantErr: true,
+		},
+		{
+			name:    "Path climbing out of the directory",
+			key:     "../outside.png",
+			wantErr: true,
+		},
+		{
+			name:    "Absolute path",
+			key:     "/etc/passwd",
+			wantErr: true,
+		},
+		{
+			name:    "Nested path",
+			key:     "sub/file.png",
+			wantErr: true,
+		},
+		{
+			name:    "Hidden file",
+			key:     ".upload-123",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			dir := filepath.Join(root, "media")
+			store, err := NewLocalStore(dir, "/app/assets/media/")
+			if err != nil {
+				t.Fatalf("NewLocalStore() error = %v", err)
+			}
+			ctx := context.Background()
+
+			err = store.Put(ctx, tt.key, strings.NewReader("data"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Put() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if _, err := store.Open(ctx, tt.key); (err != nil) != tt.wantErr {
+				t.Errorf("Open() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err := store.Delete(ctx, tt.key); (err != nil) != tt.wantErr {
+				t.Errorf("Delete() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			// Nothing may have been written outside the store's directory.
+			entries, _ := os.ReadDir(root)
+			if len(entries) != 1 {
+				t.Errorf("files outside the store: %v", entries)
+			}
+		})
+	}
+}
+
+func TestLocalStoreRoundTrip(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir(), "/app/assets/media/")
+	if err != nil {
+		t.Fatalf("NewLocalStore() error = %v", err)
+	}
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "a.png", strings.NewReader("image bytes")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if got := store.URL("a.png"); got != "/app/assets/media/a.png" {
+		t.Errorf("URL() = %q, want /app/assets/media/a.png", got)
+	}
+
+	rc, err := store.Open(ctx, "a.png")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(data) != "image bytes" {
+		t.Errorf("Open() read %q, want %q", data, "image bytes")
+	}
+
+	if err := store.Delete(ctx, "a.png"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := store.Delete(ctx, "a.png"); err != nil {
+		t.Errorf("Delete() of a missing key error = %v, want nil", err)
+	}
+	if _, err := store.Open(ctx, "a.png"); err == nil {
+		t.Error("Open() after Delete() error = nil, want an error")
+	}
+}
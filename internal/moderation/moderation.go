@@ -0,0 +1,140 @@
+package moderation
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+type Action string
+
+const (
+	// ActionMask replaces the word with asterisks and stores the chirp.
+	ActionMask Action = "mask"
+	// ActionReject refuses the chirp altogether.
+	ActionReject Action = "reject"
+	// ActionFlag stores the chirp untouched and queues it for review.
+	ActionFlag Action = "flag"
+)
+
+const mask = "****"
+
+func (a Action) Valid() bool {
+	return a == ActionMask || a == ActionReject || a == ActionFlag
+}
+
+type Rule struct {
+	Word   string
+	Action Action
+}
+
+type Result struct {
+	Body     string
+	Rejected []string
+	Flagged  []string
+}
+
+func (r Result) IsRejected() bool {
+	return len(r.Rejected) > 0
+}
+
+func (r Result) IsFlagged() bool {
+	return len(r.Flagged) > 0
+}
+
+type Filter struct {
+	rules map[string]Action
+}
+
+func NewFilter(rules []Rule) *Filter {
+	f := &Filter{rules: make(map[string]Action, len(rules))}
+	for _, rule := range rules {
+		f.rules[Normalize(rule.Word)] = rule.Action
+	}
+	return f
+}
+
+// Check matches every word of body against the filter. Words are runs of
+// letters and digits, so surrounding punctuation never hides a match and
+// is kept as-is when a word is masked.
+func (f *Filter) Check(body string) Result {
+	result := Result{}
+	seen := map[string]bool{}
+	var b strings.Builder
+
+	runes := []rune(body)
+	for i := 0; i < len(runes); {
+		if !isWordRune(runes[i]) {
+			b.WriteRune(runes[i])
+			i++
+			continue
+		}
+
+		end := i
+		for end < len(runes) && isWordRune(runes[end]) {
+			end++
+		}
+		word := string(runes[i:end])
+		normalized := Normalize(word)
+		i = end
+
+		action, banned := f.rules[normalized]
+		if !banned {
+			b.WriteString(word)
+			continue
+		}
+
+		switch action {
+		case ActionMask:
+			b.WriteString(mask)
+		case ActionReject:
+			b.WriteString(word)
+			if !seen[normalized] {
+				result.Rejected = append(result.Rejected, normalized)
+			}
+		case ActionFlag:
+			b.WriteString(word)
+			if !seen[normalized] {
+				result.Flagged = append(result.Flagged, normalized)
+			}
+		}
+		seen[normalized] = true
+	}
+
+	result.Body = b.String()
+	return result
+}
+
+// Normalize folds compatibility characters, strips accents and lowercases
+// word so that "Kérfuffle" and fullwidth "ｋｅｒｆｕｆｆｌｅ" match "kerfuffle".
+func Normalize(word string) string {
+	var b strings.Builder
+	for _, r := range norm.NFKD.String(word) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return norm.NFC.String(b.String())
+}
+
+// ValidateWord checks that word can be matched by a Filter and returns it
+// in normalized form.
+func ValidateWord(word string) (string, error) {
+	normalized := Normalize(strings.TrimSpace(word))
+	if normalized == "" {
+		return "", errors.New("word can't be empty")
+	}
+	for _, r := range normalized {
+		if !isWordRune(r) {
+			return "", errors.New("word must only contain letters and digits")
+		}
+	}
+	return normalized, nil
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+}
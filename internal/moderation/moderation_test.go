@@ -0,0 +1,169 @@
+package moderation
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		word string
+		want string
+	}{
+		{
+			name: "Lowercases",
+			word: "KerFuffle",
+			want: "kerfuffle",
+		},
+		{
+			name: "Strips accents",
+			word: "Kérfüffle",
+			want: "kerfuffle",
+		},
+		{
+			name: "Folds fullwidth characters",
+			word: "ｆｏｒｎａｘ",
+			want: "fornax",
+		},
+		{
+			name: "Decomposed accents",
+			word: "shérbert",
+			want: "sherbert",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Normalize(tt.word); got != tt.want {
+				t.Errorf("Normalize() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterCheck(t *testing.T) {
+	filter := NewFilter([]Rule{
+		{Word: "kerfuffle", Action: ActionMask},
+		{Word: "sharbert", Action: ActionMask},
+		{Word: "fornax", Action: ActionMask},
+		{Word: "blorp", Action: ActionReject},
+		{Word: "zonk", Action: ActionFlag},
+	})
+
+	tests := []struct {
+		name         string
+		body         string
+		wantBody     string
+		wantRejected []string
+		wantFlagged  []string
+	}{
+		{
+			name:     "Clean body is untouched",
+			body:     "Nothing to see here.",
+			wantBody: "Nothing to see here.",
+		},
+		{
+			name:     "Masks hyphenated words",
+			body:     "This is a kerfuffle-free chirp?",
+			wantBody: "This is a ****-free chirp?",
+		},
+		{
+			name:     "Masks regardless of case",
+			body:     "I had something interesting for breakfast KERFUFFLE",
+			wantBody: "I had something interesting for breakfast ****",
+		},
+		{
+			name:     "Masks next to punctuation",
+			body:     "Kerfuffle! What a sharbert, honestly.",
+			wantBody: "****! What a ****, honestly.",
+		},
+		{
+			name:     "Masks accented and fullwidth forms",
+			body:     "Kérfuffle and ｆｏｒｎａｘ",
+			wantBody: "**** and ****",
+		},
+		{
+			name:     "Handles tabs and repeated spaces",
+			body:     "fornax\t\tfornax  end",
+			wantBody: "****\t\t****  end",
+		},
+		{
+			name:     "Does not match inside longer words",
+			body:     "sharberts are fine",
+			wantBody: "sharberts are fine",
+		},
+		{
+			name:         "Rejects and reports each word once",
+			body:         "blorp, BLORP!",
+			wantBody:     "blorp, BLORP!",
+			wantRejected: []string{"blorp"},
+		},
+		{
+			name:        "Flags without changing the body",
+			body:        "zonk zonk",
+			wantBody:    "zonk zonk",
+			wantFlagged: []string{"zonk"},
+		},
+		{
+			name:         "Mixed actions",
+			body:         "kerfuffle zonk blorp",
+			wantBody:     "**** zonk blorp",
+			wantRejected: []string{"blorp"},
+			wantFlagged:  []string{"zonk"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := filter.Check(tt.body)
+			if got.Body != tt.wantBody {
+				t.Errorf("Check() body = %q, want %q", got.Body, tt.wantBody)
+			}
+			if !reflect.DeepEqual(got.Rejected, tt.wantRejected) {
+				t.Errorf("Check() rejected = %v, want %v", got.Rejected, tt.wantRejected)
+			}
+			if !reflect.DeepEqual(got.Flagged, tt.wantFlagged) {
+				t.Errorf("Check() flagged = %v, want %v", got.Flagged, tt.wantFlagged)
+			}
+		})
+	}
+}
+
+func TestValidateWord(t *testing.T) {
+	tests := []struct {
+		name    string
+		word    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "Valid word is normalized",
+			word: " Fornax ",
+			want: "fornax",
+		},
+		{
+			name:    "Empty word",
+			word:    "   ",
+			wantErr: true,
+		},
+		{
+			name:    "Phrases are not supported",
+			word:    "two words",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateWord(tt.word)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateWord() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ValidateWord() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
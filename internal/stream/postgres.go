@@ -0,0 +1,77 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+
+	database "github.com/JosueAD95/Server-course/internal/database"
+)
+
+const notifyChannel = "chirp_events"
+
+// PostgresBroker shares events between server instances through Postgres
+// LISTEN/NOTIFY. Every instance feeds what it hears into a local Hub, so
+// event IDs and resume history are per instance; a client that reconnects
+// to another one is sent an EventReset.
+type PostgresBroker struct {
+	db       *database.Queries
+	listener *pq.Listener
+	hub      *Hub
+}
+
+func NewPostgresBroker(db *database.Queries, dbURL string, historySize int) (*PostgresBroker, error) {
+	listener := pq.NewListener(dbURL, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Chirp stream listener error: %s", err)
+		}
+	})
+	if err := listener.Listen(notifyChannel); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	b := &PostgresBroker{
+		db:       db,
+		listener: listener,
+		hub:      NewHub(historySize),
+	}
+	go b.run()
+	return b, nil
+}
+
+func (b *PostgresBroker) Publish(ctx context.Context, e Event) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return b.db.NotifyChirpEvent(ctx, string(payload))
+}
+
+func (b *PostgresBroker) Subscribe(lastEventID string) ([]Event, *Subscription, bool) {
+	return b.hub.Subscribe(lastEventID)
+}
+
+func (b *PostgresBroker) Close() error {
+	b.hub.Close()
+	return b.listener.Close()
+}
+
+func (b *PostgresBroker) run() {
+	for n := range b.listener.Notify {
+		// A nil notification means the connection was re-established and
+		// anything sent in between was lost.
+		if n == nil {
+			continue
+		}
+		e := Event{}
+		if err := json.Unmarshal([]byte(n.Extra), &e); err != nil {
+			log.Printf("Error decoding chirp stream event: %s", err)
+			continue
+		}
+		b.hub.Publish(context.Background(), e)
+	}
+}
@@ -0,0 +1,195 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type EventType string
+
+const (
+	EventChirpCreated EventType = "chirp.created"
+	EventChirpDeleted EventType = "chirp.deleted"
+
+	// EventReset tells a resuming client that events may have been missed,
+	// because the server restarted or the history no longer reaches back
+	// to its Last-Event-ID. It should reload what it shows.
+	EventReset EventType = "stream.reset"
+)
+
+// Event is a change to a chirp pushed to stream subscribers. ID is assigned
+// by the Hub when the event is published: a sequence number that grows by
+// one per event, prefixed with the Hub's epoch so that IDs from before a
+// restart are never mistaken for current ones.
+type Event struct {
+	ID       string          `json:"-"`
+	Type     EventType       `json:"type"`
+	ChirpID  uuid.UUID       `json:"chirp_id"`
+	AuthorID uuid.UUID       `json:"author_id"`
+	Data     json.RawMessage `json:"data"`
+
+	seq uint64
+}
+
+// Broker fans chirp events out to subscribers. Subscribe returns the
+// buffered events published after lastEventID, so clients can resume after
+// a reconnect, together with a subscription for everything that follows.
+// resumed is false when lastEventID is set but the backlog can't be known
+// to be complete; the client should then be sent an EventReset.
+type Broker interface {
+	Publish(ctx context.Context, e Event) error
+	Subscribe(lastEventID string) (backlog []Event, sub *Subscription, resumed bool)
+	Close() error
+}
+
+const (
+	DefaultHistorySize = 1024
+	subscriberBuffer   = 64
+)
+
+// Hub is an in-process Broker. It only reaches subscribers connected to
+// the same server; see PostgresBroker for running several instances.
+type Hub struct {
+	mu      sync.Mutex
+	epoch   string
+	lastID  uint64
+	history []Event
+	next    int
+	full    bool
+	closed  bool
+	subs    map[*Subscription]struct{}
+}
+
+type Subscription struct {
+	C   <-chan Event
+	c   chan Event
+	hub *Hub
+}
+
+func NewHub(historySize int) *Hub {
+	if historySize < 1 {
+		historySize = DefaultHistorySize
+	}
+	return &Hub{
+		epoch:   strconv.FormatInt(time.Now().UnixNano(), 36),
+		history: make([]Event, historySize),
+		subs:    map[*Subscription]struct{}{},
+	}
+}
+
+func (h *Hub) Publish(ctx context.Context, e Event) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastID++
+	e.seq = h.lastID
+	e.ID = h.epoch + "-" + strconv.FormatUint(e.seq, 10)
+	h.history[h.next] = e
+	h.next = (h.next + 1) % len(h.history)
+	if h.next == 0 {
+		h.full = true
+	}
+
+	for sub := range h.subs {
+		select {
+		case sub.c <- e:
+		default:
+			// The subscriber can't keep up. Dropping it makes the client
+			// reconnect and resume from its Last-Event-ID instead of
+			// silently missing events.
+			h.remove(sub)
+		}
+	}
+	return nil
+}
+
+func (h *Hub) Subscribe(lastEventID string) ([]Event, *Subscription, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	backlog := []Event{}
+	resumed := true
+	if lastEventID != "" {
+		lastSeq, ok := h.parseID(lastEventID)
+		buffered := h.buffered()
+		switch {
+		case !ok:
+			resumed = false
+		case len(buffered) > 0 && buffered[0].seq > lastSeq+1:
+			// Events between lastSeq and the oldest retained one are gone.
+			resumed = false
+			fallthrough
+		default:
+			for _, e := range buffered {
+				if e.seq > lastSeq {
+					backlog = append(backlog, e)
+				}
+			}
+		}
+	}
+
+	c := make(chan Event, subscriberBuffer)
+	sub := &Subscription{C: c, c: c, hub: h}
+	if h.closed {
+		close(c)
+		return backlog, sub, resumed
+	}
+	h.subs[sub] = struct{}{}
+	return backlog, sub, resumed
+}
+
+// parseID returns the sequence number of an ID this Hub assigned. IDs
+// from before a restart, or that were never assigned, are refused.
+func (h *Hub) parseID(id string) (uint64, bool) {
+	epoch, seq, ok := strings.Cut(id, "-")
+	if !ok || epoch != h.epoch {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil || n > h.lastID {
+		return 0, false
+	}
+	return n, true
+}
+
+// Close ends every subscription so that long-lived stream requests return
+// and the server can shut down. Later subscriptions are closed right away.
+func (h *Hub) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for sub := range h.subs {
+		h.remove(sub)
+	}
+	return nil
+}
+
+// Close stops delivery and closes C. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
+
+func (h *Hub) remove(sub *Subscription) {
+	if _, ok := h.subs[sub]; !ok {
+		return
+	}
+	delete(h.subs, sub)
+	close(sub.c)
+}
+
+// buffered returns the retained events, oldest first.
+func (h *Hub) buffered() []Event {
+	if !h.full {
+		return h.history[:h.next]
+	}
+	return append(append([]Event{}, h.history[h.next:]...), h.history[:h.next]...)
+}
@@ -0,0 +1,187 @@
+package stream
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func publishN(t *testing.T, h *Hub, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if err := h.Publish(context.Background(), Event{Type: EventChirpCreated, ChirpID: uuid.New()}); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+	}
+}
+
+// eventID is the ID h gave, or would give, its n-th event.
+func eventID(h *Hub, n uint64) string {
+	return h.epoch + "-" + strconv.FormatUint(n, 10)
+}
+
+func TestHubSubscribe(t *testing.T) {
+	tests := []struct {
+		name        string
+		historySize int
+		published   int
+		lastEventID func(h *Hub) string
+		wantBacklog []uint64
+		wantResumed bool
+	}{
+		{
+			name:        "New subscriber gets no backlog",
+			historySize: 10,
+			published:   3,
+			lastEventID: func(h *Hub) string { return "" },
+			wantBacklog: []uint64{},
+			wantResumed: true,
+		},
+		{
+			name:        "Resume replays missed events",
+			historySize: 10,
+			published:   5,
+			lastEventID: func(h *Hub) string { return eventID(h, 3) },
+			wantBacklog: []uint64{4, 5},
+			wantResumed: true,
+		},
+		{
+			name:        "Resume beyond the retained history",
+			historySize: 3,
+			published:   6,
+			lastEventID: func(h *Hub) string { return eventID(h, 1) },
+			wantBacklog: []uint64{4, 5, 6},
+			wantResumed: false,
+		},
+		{
+			name:        "Resume from the event before the retained history",
+			historySize: 3,
+			published:   6,
+			lastEventID: func(h *Hub) string { return eventID(h, 3) },
+			wantBacklog: []uint64{4, 5, 6},
+			wantResumed: true,
+		},
+		{
+			name:        "Up to date subscriber",
+			historySize: 10,
+			published:   2,
+			lastEventID: func(h *Hub) string { return eventID(h, 2) },
+			wantBacklog: []uint64{},
+			wantResumed: true,
+		},
+		{
+			name:        "ID from before a restart",
+			historySize: 10,
+			published:   5,
+			lastEventID: func(h *Hub) string { return "previous-3" },
+			wantBacklog: []uint64{},
+			wantResumed: false,
+		},
+		{
+			name:        "ID the hub hasn't reached",
+			historySize: 10,
+			published:   2,
+			lastEventID: func(h *Hub) string { return eventID(h, 7) },
+			wantBacklog: []uint64{},
+			wantResumed: false,
+		},
+		{
+			name:        "ID from before epochs were sent",
+			historySize: 10,
+			published:   5,
+			lastEventID: func(h *Hub) string { return "3" },
+			wantBacklog: []uint64{},
+			wantResumed: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHub(tt.historySize)
+			publishN(t, h, tt.published)
+
+			backlog, sub, resumed := h.Subscribe(tt.lastEventID(h))
+			defer sub.Close()
+
+			if resumed != tt.wantResumed {
+				t.Errorf("Subscribe() resumed = %v, want %v", resumed, tt.wantResumed)
+			}
+			got := make([]uint64, len(backlog))
+			for i, e := range backlog {
+				got[i] = e.seq
+				if e.ID != eventID(h, e.seq) {
+					t.Errorf("event ID = %q, want %q", e.ID, eventID(h, e.seq))
+				}
+			}
+			if len(got) != len(tt.wantBacklog) {
+				t.Fatalf("Subscribe() backlog = %v, want %v", got, tt.wantBacklog)
+			}
+			for i := range got {
+				if got[i] != tt.wantBacklog[i] {
+					t.Fatalf("Subscribe() backlog = %v, want %v", got, tt.wantBacklog)
+				}
+			}
+		})
+	}
+}
+
+func TestHubFanOut(t *testing.T) {
+	h := NewHub(10)
+	_, first, _ := h.Subscribe("")
+	_, second, _ := h.Subscribe("")
+	defer first.Close()
+
+	publishN(t, h, 1)
+	second.Close()
+	publishN(t, h, 1)
+
+	if e := <-first.C; e.seq != 1 {
+		t.Errorf("first subscriber got event %d, want 1", e.seq)
+	}
+	if e := <-first.C; e.seq != 2 {
+		t.Errorf("first subscriber got event %d, want 2", e.seq)
+	}
+	if e := <-second.C; e.seq != 1 {
+		t.Errorf("second subscriber got event %d, want 1", e.seq)
+	}
+	if _, ok := <-second.C; ok {
+		t.Errorf("second subscriber channel still open after Close()")
+	}
+}
+
+func TestHubDropsSlowSubscriber(t *testing.T) {
+	h := NewHub(10)
+	_, sub, _ := h.Subscribe("")
+
+	publishN(t, h, subscriberBuffer+1)
+
+	received := 0
+	for range sub.C {
+		received++
+	}
+	if received != subscriberBuffer {
+		t.Errorf("slow subscriber received %d events before being dropped, want %d", received, subscriberBuffer)
+	}
+	sub.Close()
+}
+
+func TestHubClose(t *testing.T) {
+	h := NewHub(10)
+	_, before, _ := h.Subscribe("")
+
+	if err := h.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	_, after, _ := h.Subscribe("")
+
+	if _, ok := <-before.C; ok {
+		t.Errorf("subscription made before Close() is still open")
+	}
+	if _, ok := <-after.C; ok {
+		t.Errorf("subscription made after Close() is open")
+	}
+	before.Close()
+	after.Close()
+}
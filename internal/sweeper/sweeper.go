@@ -0,0 +1,83 @@
+package sweeper
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Task is one cleanup pass. It should stop early when ctx is cancelled.
+type Task func(ctx context.Context) error
+
+// Sweeper runs a Task on a fixed interval in the background until stopped.
+type Sweeper struct {
+	name     string
+	interval time.Duration
+	task     Task
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func New(name string, interval time.Duration, task Task) *Sweeper {
+	return &Sweeper{
+		name:     name,
+		interval: interval,
+		task:     task,
+	}
+}
+
+// Start runs the task once right away and then on every interval. Calling
+// Start on a running Sweeper does nothing.
+func (s *Sweeper) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	go s.run(ctx, s.done)
+}
+
+// Stop cancels a pass in progress and waits for the goroutine to exit, or
+// for ctx to expire, whichever comes first.
+func (s *Sweeper) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	cancel, done := s.cancel, s.done
+	s.cancel, s.done = nil, nil
+	s.mu.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Sweeper) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.task(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Error running %s sweep: %s", s.name, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
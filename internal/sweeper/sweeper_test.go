@@ -0,0 +1,62 @@
+package sweeper
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSweeperRunsUntilStopped(t *testing.T) {
+	runs := atomic.Int32{}
+	ran := make(chan struct{}, 10)
+	s := New("test", time.Millisecond, func(ctx context.Context) error {
+		runs.Add(1)
+		select {
+		case ran <- struct{}{}:
+		default:
+		}
+		return errors.New("ignored")
+	})
+
+	s.Start()
+	s.Start()
+	for i := 0; i < 3; i++ {
+		select {
+		case <-ran:
+		case <-time.After(time.Second):
+			t.Fatalf("task ran %d times, want at least 3", runs.Load())
+		}
+	}
+
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	stopped := runs.Load()
+	time.Sleep(10 * time.Millisecond)
+	if got := runs.Load(); got != stopped {
+		t.Errorf("task ran %d times after Stop(), want 0", got-stopped)
+	}
+	if err := s.Stop(context.Background()); err != nil {
+		t.Errorf("second Stop() error = %v", err)
+	}
+}
+
+func TestSweeperStopCancelsTask(t *testing.T) {
+	started := make(chan struct{})
+	s := New("test", time.Hour, func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	s.Start()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := s.Stop(ctx); err != nil {
+		t.Errorf("Stop() error = %v", err)
+	}
+}
@@ -1,20 +1,50 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"database/sql"
+	"errors"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	handler "github.com/JosueAD95/Server-course/handlers"
+	"github.com/JosueAD95/Server-course/internal/auth"
 	db "github.com/JosueAD95/Server-course/internal/database"
+	"github.com/JosueAD95/Server-course/internal/export"
+	"github.com/JosueAD95/Server-course/internal/mailer"
+	"github.com/JosueAD95/Server-course/internal/media"
+	"github.com/JosueAD95/Server-course/internal/stream"
+	"github.com/JosueAD95/Server-course/internal/sweeper"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
 
+const (
+	defaultRefreshTokenTTL       = 60 * 24 * time.Hour
+	defaultTokenSweepInterval    = time.Hour
+	defaultRevokedTokenRetention = 7 * 24 * time.Hour
+	defaultLoginLockoutThreshold = 10
+	defaultLoginLockoutDuration  = 15 * time.Minute
+	defaultPasswordMinLength     = 8
+	defaultAccountDeletionGrace  = 30 * 24 * time.Hour
+	defaultMailWorkers           = 4
+	defaultMailQueueSize         = 100
+	shutdownTimeout              = 10 * time.Second
+)
+
 func main() {
 	const port = "8080"
 	const filepath = "."
+	const mediaDir = filepath + "/assets/media"
+	const mediaURL = "/app/assets/media"
 
 	godotenv.Load()
 	dbURL := os.Getenv("DB_URL")
@@ -22,62 +52,456 @@ func main() {
 		log.Fatal("DB_URL must be set")
 	}
 
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		log.Fatal("JWT_SECRET environment variable is not set ")
+	// JWT_KEYS_FILE points at a key ring description and takes over from
+	// JWT_SECRET. List the old secret there as the "legacy" key, with a
+	// retire_at, to keep tokens it signed valid until they expire.
+	var jwtKeys *auth.KeyRing
+	if keysFile := os.Getenv("JWT_KEYS_FILE"); keysFile != "" {
+		keys, err := auth.LoadKeyRing(keysFile)
+		if err != nil {
+			log.Fatalf("Error loading JWT keys: %s", err)
+		}
+		jwtKeys = keys
+	} else {
+		jwtSecret := os.Getenv("JWT_SECRET")
+		if jwtSecret == "" {
+			log.Fatal("JWT_SECRET environment variable is not set ")
+		}
+		keys, err := auth.NewKeyRing(auth.NewHMACKey(auth.LegacyKeyID, []byte(jwtSecret)))
+		if err != nil {
+			log.Fatalf("Error creating JWT keys: %s", err)
+		}
+		jwtKeys = keys
 	}
 	polkaKey := os.Getenv("POLKA_KEY")
 	if polkaKey == "" {
 		log.Fatal("POLKA_KEY environment variable is not set ")
 	}
 
+	refreshTokenTTL := durationEnv("REFRESH_TOKEN_TTL", defaultRefreshTokenTTL)
+	sweepInterval := durationEnv("TOKEN_SWEEP_INTERVAL", defaultTokenSweepInterval)
+	revokedRetention := durationEnv("REVOKED_TOKEN_RETENTION", defaultRevokedTokenRetention)
+
+	// Many users can share an address, so it takes ten times the failures
+	// to lock one out.
+	loginThrottle := auth.LoginThrottle{
+		BaseDelay: time.Second,
+		MaxDelay:  time.Minute,
+		Threshold: intEnv("LOGIN_LOCKOUT_THRESHOLD", defaultLoginLockoutThreshold),
+		Lockout:   durationEnv("LOGIN_LOCKOUT_DURATION", defaultLoginLockoutDuration),
+	}
+	ipLoginThrottle := loginThrottle
+	ipLoginThrottle.Threshold *= 10
+
+	// BREACHED_PASSWORDS_FILE is a list of SHA-1 digests of breached
+	// passwords, sorted by digest like the Pwned Passwords download, that
+	// can't be used for new passwords. It is searched on disk.
+	passwordPolicy := auth.DefaultPasswordPolicy
+	passwordPolicy.MinLength = intEnv("PASSWORD_MIN_LENGTH", defaultPasswordMinLength)
+	if breachedFile := os.Getenv("BREACHED_PASSWORDS_FILE"); breachedFile != "" {
+		breached, err := auth.OpenBreachedPasswords(breachedFile)
+		if err != nil {
+			log.Fatalf("Error opening breached passwords: %s", err)
+		}
+		passwordPolicy.Breached = breached
+	}
+
 	dbConn, err := sql.Open("postgres", dbURL)
 	if err != nil {
 		log.Fatalf("Error opening database: %s", err)
 	}
 
+	blobs, err := media.NewLocalStore(mediaDir, mediaURL)
+	if err != nil {
+		log.Fatalf("Error preparing media directory: %s", err)
+	}
+
+	queries := db.New(dbConn)
+
+	// Without SMTP_HOST, mail is written to MAIL_OUTBOX_DIR instead of
+	// being sent. It holds live reset links, so it is kept out of the
+	// directory served under /app/.
+	mailFrom := os.Getenv("MAIL_FROM")
+	if mailFrom == "" {
+		mailFrom = "Chirpy <no-reply@localhost>"
+	}
+	var mail mailer.Mailer
+	if smtpHost := os.Getenv("SMTP_HOST"); smtpHost != "" {
+		smtpPort := os.Getenv("SMTP_PORT")
+		if smtpPort == "" {
+			smtpPort = "587"
+		}
+		mail = mailer.NewSMTPMailer(smtpHost, smtpPort, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), mailFrom)
+	} else {
+		outboxDir := privateDirEnv("MAIL_OUTBOX_DIR", "outbox", filepath)
+		outbox, err := mailer.NewFileOutbox(outboxDir, mailFrom)
+		if err != nil {
+			log.Fatalf("Error preparing mail outbox: %s", err)
+		}
+		mail = outbox
+	}
+	// Mail asked for by requests is sent by MAIL_WORKERS workers; past
+	// MAIL_QUEUE_SIZE waiting messages, more are dropped.
+	mailQueue := mailer.NewQueue(intEnv("MAIL_WORKERS", defaultMailWorkers), intEnv("MAIL_QUEUE_SIZE", defaultMailQueueSize))
+
+	// Data exports are kept in EXPORT_DIR, outside the directory served
+	// under /app/, until they expire. Download links are signed with
+	// EXPORT_SIGNING_KEY; without one, a key is made up at startup and links
+	// stop working on restart.
+	exportDir := privateDirEnv("EXPORT_DIR", "exports", filepath)
+	if err := os.MkdirAll(exportDir, 0o700); err != nil {
+		log.Fatalf("Error preparing export directory: %s", err)
+	}
+	exports, err := media.NewLocalStore(exportDir, "")
+	if err != nil {
+		log.Fatalf("Error preparing export directory: %s", err)
+	}
+	exportKey := []byte(os.Getenv("EXPORT_SIGNING_KEY"))
+	if len(exportKey) == 0 {
+		exportKey = make([]byte, 32)
+		if _, err := rand.Read(exportKey); err != nil {
+			log.Fatalf("Error making export signing key: %s", err)
+		}
+	}
+	exportLinks, err := export.NewSigner(exportKey)
+	if err != nil {
+		log.Fatalf("Error loading export signing key: %s", err)
+	}
+
+	baseURL := os.Getenv("BASE_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:" + port
+	}
+
+	// Set CHIRP_STREAM=postgres when running more than one instance so
+	// stream subscribers see chirps created on any of them.
+	var chirpStream stream.Broker = stream.NewHub(stream.DefaultHistorySize)
+	if os.Getenv("CHIRP_STREAM") == "postgres" {
+		pgStream, err := stream.NewPostgresBroker(queries, dbURL, stream.DefaultHistorySize)
+		if err != nil {
+			log.Fatalf("Error listening for chirp events: %s", err)
+		}
+		chirpStream = pgStream
+	}
+
 	apiCfg := handler.ApiConfig{
-		Db:          db.New(dbConn),
+		Db:          queries,
+		DbConn:      dbConn,
 		Environment: os.Getenv("Environment"),
-		JWTSecret:   jwtSecret,
+		JWTKeys:     jwtKeys,
 		PolkaAPIKey: polkaKey,
+		Blobs:       blobs,
+		Stream:      chirpStream,
+		Mailer:      mail,
+		MailQueue:   mailQueue,
+		BaseURL:     baseURL,
+		Exports:     exports,
+		ExportLinks: exportLinks,
+
+		RefreshTokenTTL: refreshTokenTTL,
+		PasswordPolicy:  passwordPolicy,
+		LoginThrottle:   loginThrottle,
+		IPLoginThrottle: ipLoginThrottle,
+
+		// Set REQUIRE_VERIFIED_EMAIL=true to stop users who haven't
+		// verified their email from posting chirps.
+		RequireVerifiedEmail: os.Getenv("REQUIRE_VERIFIED_EMAIL") == "true",
+		AccountDeletionGrace: durationEnv("ACCOUNT_DELETION_GRACE", defaultAccountDeletionGrace),
+	}
+
+	// ADMIN_EMAIL names an existing user to make admin at startup, for
+	// deployments that don't have one yet.
+	if adminEmail := os.Getenv("ADMIN_EMAIL"); adminEmail != "" {
+		if err := apiCfg.BootstrapAdmin(context.Background(), adminEmail); err != nil {
+			log.Printf("Error bootstrapping admin: %s", err)
+		}
 	}
 
 	mux := http.NewServeMux()
 
 	mux.Handle("/app/", apiCfg.MiddlewareMetricsInc(http.StripPrefix("/app/", http.FileServer(http.Dir(filepath)))))
 
-	mux.HandleFunc("GET /admin/metrics", apiCfg.Metrics)
+	mux.HandleFunc("GET /admin/metrics", apiCfg.RequirePermission(auth.PermissionViewMetrics, apiCfg.Metrics))
+
+	mux.HandleFunc("POST /admin/reset", apiCfg.RequirePermission(auth.PermissionResetData, apiCfg.Reset))
+
+	mux.HandleFunc("GET /admin/banned-words", apiCfg.RequirePermission(auth.PermissionModerate, apiCfg.ListBannedWords))
+
+	mux.HandleFunc("POST /admin/banned-words", apiCfg.RequirePermission(auth.PermissionModerate, apiCfg.CreateBannedWord))
+
+	mux.HandleFunc("PUT /admin/banned-words/{wordID}", apiCfg.RequirePermission(auth.PermissionModerate, apiCfg.UpdateBannedWord))
+
+	mux.HandleFunc("DELETE /admin/banned-words/{wordID}", apiCfg.RequirePermission(auth.PermissionModerate, apiCfg.DeleteBannedWord))
 
-	mux.HandleFunc("POST /admin/reset", apiCfg.Reset)
+	mux.HandleFunc("GET /admin/flagged-chirps", apiCfg.RequirePermission(auth.PermissionModerate, apiCfg.ListFlaggedChirps))
+
+	mux.HandleFunc("POST /admin/flagged-chirps/{flagID}/resolve", apiCfg.RequirePermission(auth.PermissionModerate, apiCfg.ResolveChirpFlag))
+
+	mux.HandleFunc("POST /admin/users/{userID}/role", apiCfg.RequirePermission(auth.PermissionManageRoles, apiCfg.GrantUserRole))
+
+	mux.HandleFunc("DELETE /admin/users/{userID}/role", apiCfg.RequirePermission(auth.PermissionManageRoles, apiCfg.RevokeUserRole))
+
+	mux.HandleFunc("POST /admin/users/{userID}/unlock", apiCfg.RequirePermission(auth.PermissionUnlockUsers, apiCfg.UnlockUser))
+
+	mux.HandleFunc("GET /admin/role-changes", apiCfg.RequirePermission(auth.PermissionManageRoles, apiCfg.ListRoleChanges))
 
 	mux.HandleFunc("GET /api/healthz", handler.Healthz)
 
-	mux.HandleFunc("POST /api/chirps", apiCfg.CreateChirp)
+	mux.HandleFunc("GET /.well-known/jwks.json", apiCfg.JWKS)
+
+	mux.HandleFunc("POST /api/media", apiCfg.RequireAuth(auth.ScopeChirpsWrite, apiCfg.UploadMedia))
+
+	mux.HandleFunc("POST /api/chirps", apiCfg.RequireAuth(auth.ScopeChirpsWrite, apiCfg.CreateChirp))
 
-	mux.HandleFunc("GET /api/chirps", apiCfg.GetAllChirps)
+	mux.HandleFunc("GET /api/chirps", apiCfg.OptionalAuth(auth.ScopeChirpsRead, apiCfg.GetAllChirps))
 
-	mux.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.GetChirpById)
+	mux.HandleFunc("GET /api/chirps/search", apiCfg.OptionalAuth(auth.ScopeChirpsRead, apiCfg.SearchChirps))
 
-	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.DeleteChirp)
+	mux.HandleFunc("GET /api/chirps/stream", apiCfg.OptionalAuth(auth.ScopeChirpsRead, apiCfg.StreamChirps))
+
+	mux.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.OptionalAuth(auth.ScopeChirpsRead, apiCfg.GetChirpById))
+
+	mux.HandleFunc("PATCH /api/chirps/{chirpID}", apiCfg.RequireAuth(auth.ScopeChirpsWrite, apiCfg.EditChirp))
+
+	mux.HandleFunc("GET /api/chirps/{chirpID}/revisions", apiCfg.GetChirpRevisions)
+
+	mux.HandleFunc("GET /api/chirps/{chirpID}/thread", apiCfg.OptionalAuth(auth.ScopeChirpsRead, apiCfg.GetChirpThread))
+
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.RequireAuth(auth.ScopeChirpsWrite, apiCfg.DeleteChirp))
+
+	mux.HandleFunc("POST /api/chirps/{chirpID}/likes", apiCfg.RequireAuth(auth.ScopeChirpsWrite, apiCfg.LikeChirp))
+
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/likes", apiCfg.RequireAuth(auth.ScopeChirpsWrite, apiCfg.UnlikeChirp))
+
+	mux.HandleFunc("GET /api/hashtags/trending", apiCfg.GetTrendingHashtags)
+
+	mux.HandleFunc("GET /api/hashtags/{tag}/chirps", apiCfg.OptionalAuth(auth.ScopeChirpsRead, apiCfg.GetChirpsByHashtag))
 
 	mux.HandleFunc("POST /api/users", apiCfg.AddUser)
 
-	mux.HandleFunc("PUT /api/users", apiCfg.UpdateUserCredentials)
+	mux.HandleFunc("PATCH /api/users", apiCfg.RequireAuth(auth.ScopeProfileWrite, apiCfg.UpdateUser))
+
+	// PUT is kept for older clients and takes the same partial updates.
+	mux.HandleFunc("PUT /api/users", apiCfg.RequireAuth(auth.ScopeProfileWrite, apiCfg.UpdateUser))
+
+	mux.HandleFunc("DELETE /api/users/me", apiCfg.RequireAuth(auth.ScopeInteractive, apiCfg.DeleteAccount))
+
+	mux.HandleFunc("GET /api/users/me/export", apiCfg.RequireAuth(auth.ScopeInteractive, apiCfg.ExportUserData))
+
+	mux.HandleFunc("GET /api/exports/{exportID}", apiCfg.DownloadDataExport)
+
+	mux.HandleFunc("POST /api/users/verify-email", apiCfg.VerifyEmail)
+
+	mux.HandleFunc("POST /api/users/verify-email/resend", apiCfg.RequireAuth(auth.ScopeInteractive, apiCfg.ResendEmailVerification))
+
+	mux.HandleFunc("POST /api/users/mfa/totp", apiCfg.RequireAuth(auth.ScopeInteractive, apiCfg.StartTOTPEnrolment))
+
+	mux.HandleFunc("POST /api/users/mfa/totp/confirm", apiCfg.RequireAuth(auth.ScopeInteractive, apiCfg.ConfirmTOTPEnrolment))
+
+	mux.HandleFunc("POST /api/users/{userID}/follow", apiCfg.RequireAuth(auth.ScopeProfileWrite, apiCfg.FollowUser))
+
+	mux.HandleFunc("DELETE /api/users/{userID}/follow", apiCfg.RequireAuth(auth.ScopeProfileWrite, apiCfg.UnfollowUser))
+
+	mux.HandleFunc("GET /api/users/{userID}/followers", apiCfg.GetFollowers)
+
+	mux.HandleFunc("GET /api/users/{userID}/following", apiCfg.GetFollowing)
+
+	mux.HandleFunc("GET /api/users/{userID}/likes", apiCfg.OptionalAuth(auth.ScopeChirpsRead, apiCfg.GetUserLikes))
+
+	mux.HandleFunc("GET /api/timeline", apiCfg.RequireAuth(auth.ScopeChirpsRead, apiCfg.GetTimeline))
 
 	mux.HandleFunc("POST /api/login", apiCfg.Login)
 
+	mux.HandleFunc("POST /api/login/mfa", apiCfg.CompleteMFALogin)
+
+	mux.HandleFunc("POST /api/password-reset", apiCfg.RequestPasswordReset)
+
+	mux.HandleFunc("POST /api/password-reset/confirm", apiCfg.ConfirmPasswordReset)
+
 	mux.HandleFunc("POST /api/refresh", apiCfg.RefreshToken)
 
 	mux.HandleFunc("POST /api/revoke", apiCfg.RevokeToken)
 
+	mux.HandleFunc("GET /api/sessions", apiCfg.RequireAuth(auth.ScopeInteractive, apiCfg.ListSessions))
+
+	mux.HandleFunc("DELETE /api/sessions/{sessionID}", apiCfg.RequireAuth(auth.ScopeInteractive, apiCfg.RevokeSession))
+
+	mux.HandleFunc("POST /api/sessions/revoke-all", apiCfg.RequireAuth(auth.ScopeInteractive, apiCfg.RevokeAllSessions))
+
+	mux.HandleFunc("POST /api/tokens", apiCfg.RequireAuth(auth.ScopeInteractive, apiCfg.CreatePersonalAccessToken))
+
+	mux.HandleFunc("GET /api/tokens", apiCfg.RequireAuth(auth.ScopeInteractive, apiCfg.ListPersonalAccessTokens))
+
+	mux.HandleFunc("DELETE /api/tokens/{tokenID}", apiCfg.RequireAuth(auth.ScopeInteractive, apiCfg.RevokePersonalAccessToken))
+
 	mux.HandleFunc("POST /api/polka/webhooks", apiCfg.UpgradeUser)
 
 	server := &http.Server{
 		Handler: mux,
 		Addr:    ":" + port,
 	}
+	// Stream requests only end when the client goes away, so they have to
+	// be told to finish before Shutdown can complete.
+	server.RegisterOnShutdown(func() { chirpStream.Close() })
+
+	// Expired tokens go right away, revoked ones after revokedRetention.
+	// Rotated tokens are kept until they expire so that a late replay is
+	// still recognised as reuse.
+	tokenSweeper := sweeper.New("refresh token", sweepInterval, func(ctx context.Context) error {
+		deleted, err := apiCfg.Db.DeleteStaleRefreshTokens(ctx, sql.NullTime{
+			Time:  time.Now().Add(-revokedRetention),
+			Valid: true,
+		})
+		if deleted > 0 {
+			log.Printf("Deleted %d stale refresh tokens", deleted)
+		}
+		return err
+	})
+	tokenSweeper.Start()
+
+	// Failures older than the lockout no longer count, see countLoginFailure.
+	loginSweeper := sweeper.New("login attempt", sweepInterval, func(ctx context.Context) error {
+		if _, err := apiCfg.Db.DeleteStaleLoginAttempts(ctx, time.Now().Add(-loginThrottle.Lockout)); err != nil {
+			return err
+		}
+		_, err := apiCfg.Db.DeleteExpiredMFATokens(ctx)
+		return err
+	})
+	loginSweeper.Start()
+
+	mailTokenSweeper := sweeper.New("emailed token", sweepInterval, func(ctx context.Context) error {
+		if _, err := apiCfg.Db.DeleteStalePasswordResetTokens(ctx); err != nil {
+			return err
+		}
+		_, err := apiCfg.Db.DeleteStaleEmailVerificationTokens(ctx)
+		return err
+	})
+	mailTokenSweeper.Start()
+
+	accountSweeper := sweeper.New("account deletion", sweepInterval, func(ctx context.Context) error {
+		deleted, err := apiCfg.DeleteDueAccounts(ctx)
+		if deleted > 0 {
+			log.Printf("Deleted %d accounts past their grace period", deleted)
+		}
+		return err
+	})
+	accountSweeper.Start()
+
+	exportSweeper := sweeper.New("data export", sweepInterval, apiCfg.DeleteStaleDataExports)
+	exportSweeper.Start()
+
+	// A deleted chirp is only kept as a tombstone while it has replies.
+	// Removing one can leave its parent without any, hence the loop.
+	tombstoneSweeper := sweeper.New("chirp tombstone", sweepInterval, func(ctx context.Context) error {
+		for {
+			deleted, err := apiCfg.Db.DeleteOrphanedTombstones(ctx)
+			if err != nil || deleted == 0 {
+				return err
+			}
+			log.Printf("Deleted %d chirp tombstones without replies", deleted)
+		}
+	})
+	tombstoneSweeper.Start()
+
+	mediaSweeper := sweeper.New("unattached media", sweepInterval, apiCfg.DeleteUnattachedMedia)
+	mediaSweeper.Start()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Error starting server: %s", err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Printf("Shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Error shutting down server: %s", err)
+	}
+	if err := mailQueue.Stop(shutdownCtx); err != nil {
+		log.Printf("Error stopping mail queue: %s", err)
+	}
+	if err := tokenSweeper.Stop(shutdownCtx); err != nil {
+		log.Printf("Error stopping refresh token sweeper: %s", err)
+	}
+	if err := loginSweeper.Stop(shutdownCtx); err != nil {
+		log.Printf("Error stopping login attempt sweeper: %s", err)
+	}
+	if err := mailTokenSweeper.Stop(shutdownCtx); err != nil {
+		log.Printf("Error stopping emailed token sweeper: %s", err)
+	}
+	if err := accountSweeper.Stop(shutdownCtx); err != nil {
+		log.Printf("Error stopping account deletion sweeper: %s", err)
+	}
+	if err := exportSweeper.Stop(shutdownCtx); err != nil {
+		log.Printf("Error stopping data export sweeper: %s", err)
+	}
+	if err := tombstoneSweeper.Stop(shutdownCtx); err != nil {
+		log.Printf("Error stopping chirp tombstone sweeper: %s", err)
+	}
+	if err := mediaSweeper.Stop(shutdownCtx); err != nil {
+		log.Printf("Error stopping unattached media sweeper: %s", err)
+	}
+	dbConn.Close()
+}
 
-	log.Fatal(server.ListenAndServe())
+// durationEnv reads a duration such as "720h" from the environment, falling
+// back to def when the variable is unset.
+func durationEnv(name string, def time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Fatalf("%s must be a positive duration: %q", name, value)
+	}
+	return d
+}
 
+// intEnv reads a positive integer from the environment, falling back to def
+// when the variable is unset.
+func intEnv(name string, def int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		log.Fatalf("%s must be a positive integer: %q", name, value)
+	}
+	return n
+}
+
+// privateDirEnv reads a directory for files that must not be downloadable
+// from the environment, falling back to def under $XDG_STATE_HOME/chirpy
+// (or the temp directory). A directory inside servedDir is refused.
+func privateDirEnv(name, def, servedDir string) string {
+	dir := os.Getenv(name)
+	if dir == "" {
+		base := os.Getenv("XDG_STATE_HOME")
+		if base == "" {
+			base = os.TempDir()
+		}
+		dir = filepath.Join(base, "chirpy", def)
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		log.Fatalf("Error resolving %s: %s", name, err)
+	}
+	absServed, err := filepath.Abs(servedDir)
+	if err != nil {
+		log.Fatalf("Error resolving served directory: %s", err)
+	}
+	rel, err := filepath.Rel(absServed, absDir)
+	if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		log.Fatalf("%s must not be inside the served directory %q: %q", name, servedDir, dir)
+	}
+	return dir
 }
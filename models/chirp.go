@@ -4,21 +4,80 @@ import (
 	"time"
 
 	db "github.com/JosueAD95/Server-course/internal/database"
+	"github.com/JosueAD95/Server-course/internal/entities"
+	util "github.com/JosueAD95/Server-course/utils"
 	"github.com/google/uuid"
 )
 
 type Chirp struct {
-	Id        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	UserId    uuid.UUID `json:"user_id"`
+	Id         uuid.UUID         `json:"id"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+	Body       string            `json:"body"`
+	Entities   []entities.Entity `json:"entities"`
+	UserId     uuid.UUID         `json:"user_id"`
+	InReplyTo  *uuid.UUID        `json:"in_reply_to,omitempty"`
+	ReplyCount int64             `json:"reply_count"`
+	LikeCount  int64             `json:"like_count"`
+	LikedByMe  *bool             `json:"liked_by_me,omitempty"`
+	MediaIds   []uuid.UUID       `json:"media_ids,omitempty"`
+	Media      []Media           `json:"media"`
+	Deleted    bool              `json:"deleted,omitempty"`
 }
 
 func (c *Chirp) MapDBChirp(dbChirp db.Chirp) {
 	c.Id = dbChirp.ID
 	c.Body = dbChirp.Body
+	c.Entities = entities.Parse(dbChirp.Body)
 	c.UserId = dbChirp.UserID
 	c.CreatedAt = dbChirp.CreatedAt
 	c.UpdatedAt = dbChirp.UpdatedAt
+	c.InReplyTo = nil
+	if dbChirp.InReplyTo.Valid {
+		parent := dbChirp.InReplyTo.UUID
+		c.InReplyTo = &parent
+	}
+	c.Deleted = dbChirp.DeletedAt.Valid
+}
+
+type ChirpPage struct {
+	Chirps     []Chirp `json:"chirps"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+// NewChirpPage maps a page of rows fetched with one extra row beyond limit.
+// When the extra row is present it is dropped and the cursor is set.
+func NewChirpPage(dbChirps []db.Chirp, limit int) ChirpPage {
+	page := ChirpPage{}
+	if len(dbChirps) > limit {
+		dbChirps = dbChirps[:limit]
+		last := dbChirps[len(dbChirps)-1]
+		page.NextCursor = util.EncodeCursor(util.Cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	page.Chirps = make([]Chirp, len(dbChirps))
+	for i, c := range dbChirps {
+		page.Chirps[i].MapDBChirp(c)
+	}
+	return page
+}
+
+type ChirpRevision struct {
+	Id        uuid.UUID `json:"id"`
+	ChirpId   uuid.UUID `json:"chirp_id"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (c *ChirpRevision) MapDBChirpRevision(dbRevision db.ChirpRevision) {
+	c.Id = dbRevision.ID
+	c.ChirpId = dbRevision.ChirpID
+	c.Body = dbRevision.Body
+	c.CreatedAt = dbRevision.CreatedAt
+}
+
+type ChirpThread struct {
+	Ancestors  []Chirp `json:"ancestors"`
+	Chirp      Chirp   `json:"chirp"`
+	Replies    []Chirp `json:"replies"`
+	NextCursor string  `json:"next_cursor,omitempty"`
 }
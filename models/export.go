@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+
+	db "github.com/JosueAD95/Server-course/internal/database"
+	"github.com/google/uuid"
+)
+
+// DataExport is the state of an archive of a user's data. DownloadURL is
+// only set once the archive is ready, and works without signing in until
+// it expires.
+type DataExport struct {
+	Id          uuid.UUID  `json:"id"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	DownloadURL string     `json:"download_url,omitempty"`
+}
+
+func (e *DataExport) MapDBDataExport(dbExport db.DataExport, downloadURL string) {
+	e.Id = dbExport.ID
+	e.Status = dbExport.Status
+	e.CreatedAt = dbExport.CreatedAt
+	e.ExpiresAt = nil
+	if dbExport.ExpiresAt.Valid {
+		e.ExpiresAt = &dbExport.ExpiresAt.Time
+	}
+	e.DownloadURL = downloadURL
+}
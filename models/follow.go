@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+
+	util "github.com/JosueAD95/Server-course/utils"
+	"github.com/google/uuid"
+)
+
+type Follow struct {
+	UserId     uuid.UUID `json:"user_id"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+type FollowPage struct {
+	Follows    []Follow `json:"follows"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+// NewFollowPage works like NewChirpPage: follows holds one extra entry
+// beyond limit when there is a next page.
+func NewFollowPage(follows []Follow, limit int) FollowPage {
+	page := FollowPage{Follows: follows}
+	if len(follows) > limit {
+		page.Follows = follows[:limit]
+		last := page.Follows[len(page.Follows)-1]
+		page.NextCursor = util.EncodeCursor(util.Cursor{CreatedAt: last.FollowedAt, ID: last.UserId})
+	}
+	return page
+}
@@ -0,0 +1,24 @@
+package model
+
+import (
+	"time"
+
+	db "github.com/JosueAD95/Server-course/internal/database"
+	"github.com/google/uuid"
+)
+
+type Media struct {
+	Id          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	Url         string    `json:"url"`
+	ContentType string    `json:"content_type"`
+	SizeBytes   int64     `json:"size_bytes"`
+}
+
+func (m *Media) MapDBMedia(dbMedia db.Media, url string) {
+	m.Id = dbMedia.ID
+	m.CreatedAt = dbMedia.CreatedAt
+	m.Url = url
+	m.ContentType = dbMedia.ContentType
+	m.SizeBytes = dbMedia.SizeBytes
+}
@@ -0,0 +1,38 @@
+package model
+
+import (
+	"time"
+
+	db "github.com/JosueAD95/Server-course/internal/database"
+	"github.com/google/uuid"
+)
+
+type BannedWord struct {
+	Id        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Word      string    `json:"word"`
+	Action    string    `json:"action"`
+}
+
+func (b *BannedWord) MapDBBannedWord(dbWord db.BannedWord) {
+	b.Id = dbWord.ID
+	b.CreatedAt = dbWord.CreatedAt
+	b.UpdatedAt = dbWord.UpdatedAt
+	b.Word = dbWord.Word
+	b.Action = dbWord.Action
+}
+
+type ChirpFlag struct {
+	Id        uuid.UUID `json:"id"`
+	ChirpId   uuid.UUID `json:"chirp_id"`
+	Words     []string  `json:"words"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (f *ChirpFlag) MapDBChirpFlag(dbFlag db.ChirpFlag) {
+	f.Id = dbFlag.ID
+	f.ChirpId = dbFlag.ChirpID
+	f.Words = dbFlag.Words
+	f.CreatedAt = dbFlag.CreatedAt
+}
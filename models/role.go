@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+
+	db "github.com/JosueAD95/Server-course/internal/database"
+	"github.com/google/uuid"
+)
+
+type RoleChange struct {
+	Id        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UserId    uuid.UUID  `json:"user_id"`
+	ChangedBy *uuid.UUID `json:"changed_by"`
+	OldRole   string     `json:"old_role"`
+	NewRole   string     `json:"new_role"`
+}
+
+func (c *RoleChange) MapDBRoleChange(dbChange db.RoleChange) {
+	c.Id = dbChange.ID
+	c.CreatedAt = dbChange.CreatedAt
+	c.UserId = dbChange.UserID
+	c.ChangedBy = nil
+	if dbChange.ChangedBy.Valid {
+		c.ChangedBy = &dbChange.ChangedBy.UUID
+	}
+	c.OldRole = dbChange.OldRole
+	c.NewRole = dbChange.NewRole
+}
@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+
+	db "github.com/JosueAD95/Server-course/internal/database"
+	"github.com/google/uuid"
+)
+
+type Session struct {
+	Id         uuid.UUID `json:"id"`
+	SignedInAt time.Time `json:"signed_in_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	UserAgent  string    `json:"user_agent"`
+	IpAddress  string    `json:"ip_address"`
+}
+
+func (s *Session) MapDBSession(dbSession db.ListUserSessionsRow) {
+	s.Id = dbSession.FamilyID
+	s.SignedInAt = dbSession.SignedInAt
+	s.LastUsedAt = dbSession.LastUsedAt
+	s.ExpiresAt = dbSession.ExpiresAt
+	s.UserAgent = dbSession.UserAgent
+	s.IpAddress = dbSession.IpAddress
+}
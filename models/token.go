@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+
+	db "github.com/JosueAD95/Server-course/internal/database"
+	"github.com/google/uuid"
+)
+
+// PersonalAccessToken never carries the token itself except in the
+// response to its creation.
+type PersonalAccessToken struct {
+	Id         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	Token      string     `json:"token,omitempty"`
+}
+
+func (t *PersonalAccessToken) MapDBPersonalAccessToken(dbToken db.PersonalAccessToken) {
+	t.Id = dbToken.ID
+	t.Name = dbToken.Name
+	t.Scopes = dbToken.Scopes
+	t.CreatedAt = dbToken.CreatedAt
+	t.ExpiresAt = nil
+	if dbToken.ExpiresAt.Valid {
+		t.ExpiresAt = &dbToken.ExpiresAt.Time
+	}
+	t.LastUsedAt = nil
+	if dbToken.LastUsedAt.Valid {
+		t.LastUsedAt = &dbToken.LastUsedAt.Time
+	}
+}
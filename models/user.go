@@ -8,12 +8,16 @@ import (
 )
 
 type User struct {
-	ID          uuid.UUID `json:"id"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	Email       string    `json:"email"`
-	Password    string    `json:"password,omitempty"`
-	IsChirpyRed bool      `json:"is_chirpy_red"`
+	ID            uuid.UUID  `json:"id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	Email         string     `json:"email"`
+	Password      string     `json:"password,omitempty"`
+	IsChirpyRed   bool       `json:"is_chirpy_red"`
+	Role          string     `json:"role,omitempty"`
+	EmailVerified bool       `json:"email_verified"`
+	PendingEmail  string     `json:"pending_email,omitempty"`
+	DeleteAfter   *time.Time `json:"delete_after,omitempty"`
 }
 
 func (u *User) MapRowUser(dbUser db.CreateUserRow) {
@@ -29,5 +33,13 @@ func (u *User) MapDbUser(dbUser db.User) {
 	u.Email = dbUser.Email
 	u.CreatedAt = dbUser.CreatedAt
 	u.UpdatedAt = dbUser.UpdatedAt
+	u.IsChirpyRed = dbUser.IsChirpyRed
+	u.Role = dbUser.Role
+	u.EmailVerified = dbUser.EmailVerifiedAt.Valid
+	u.PendingEmail = dbUser.PendingEmail.String
+	u.DeleteAfter = nil
+	if dbUser.DeleteAfter.Valid {
+		u.DeleteAfter = &dbUser.DeleteAfter.Time
+	}
 	u.Password = ""
 }
@@ -1,16 +1,40 @@
 -- name: SaveRefreshToken :execresult
-INSERT INTO refresh_tokens(token, created_at, updated_at, user_id, expires_at, revoked_at)
-VALUES ($1, NOW(), NOW(), $2, $3, NULL);
+INSERT INTO refresh_tokens(token_hash, created_at, updated_at, user_id, expires_at, revoked_at, family_id,
+                           user_agent, ip_address, signed_in_at, last_used_at)
+VALUES ($1, NOW(), NOW(), $2, $3, NULL, $4, $5, $6, $7, NOW());
 
 
--- name: GetUserIdFromRefreshToken :one
-SELECT user_id, revoked_at 
+-- name: RotateRefreshToken :one
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    rotated_at = NOW(),
+    updated_at = NOW()
+WHERE token_hash = $1 AND revoked_at IS NULL AND expires_at > NOW()
+
+RETURNING user_id, family_id, signed_in_at;
+
+
+-- name: GetRefreshTokenFamily :one
+SELECT user_id, family_id, expires_at, revoked_at, rotated_at
 FROM refresh_tokens
-WHERE token = $1;
+WHERE token_hash = $1;
+
+
+-- name: RevokeRefreshTokenFamily :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE family_id = $1 AND revoked_at IS NULL;
 
 
 -- name: RevokeToken :execresult
 UPDATE refresh_tokens 
 SET revoked_at = NOW(),
     updated_at = NOW()
-WHERE token = $1;
+WHERE token_hash = $1;
+
+
+-- name: DeleteStaleRefreshTokens :execrows
+DELETE FROM refresh_tokens
+WHERE expires_at <= NOW()
+   OR (revoked_at < sqlc.arg('revoked_before') AND rotated_at IS NULL);
@@ -1,24 +1,125 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
-VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2, $3)
 
-RETURNING *;
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, deleted_at;
 
--- name: GetChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
-ORDER BY created_at ASC;
+-- name: ListChirpsAsc :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at
+FROM chirps
+WHERE deleted_at IS NULL
+  AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (created_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('limit');
+
+-- name: ListChirpsDesc :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at
+FROM chirps
+WHERE deleted_at IS NULL
+  AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
 
 -- name: GetChirpsByUserId :many
-SELECT id, created_at, updated_at, body, user_id
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at
 FROM chirps
 WHERE user_id = $1
+  AND deleted_at IS NULL
 ORDER BY created_at ASC;
 
 -- name: GetChirpById :one
-SELECT id, created_at, updated_at, body, user_id
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at
 FROM chirps
 WHERE id = $1;
 
 -- name: DeleteChirp :exec
 DElETE FROM chirps
 WHERE id = $1;
+
+-- name: GetChirpByIdForUpdate :one
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at
+FROM chirps
+WHERE id = $1
+FOR UPDATE;
+
+-- name: UpdateChirpBody :one
+UPDATE chirps
+SET body = $2,
+    updated_at = NOW()
+WHERE id = $1
+
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, deleted_at;
+
+-- name: TombstoneChirp :exec
+UPDATE chirps
+SET body = '',
+    deleted_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: DeleteOrphanedTombstones :execrows
+DELETE FROM chirps c
+WHERE c.deleted_at IS NOT NULL
+  AND NOT EXISTS (SELECT 1 FROM chirps r WHERE r.in_reply_to = c.id);
+
+-- name: ChirpHasReplies :one
+SELECT EXISTS (
+  SELECT 1 FROM chirps WHERE in_reply_to = sqlc.arg('chirp_id')::uuid
+) AS has_replies;
+
+-- name: CountRepliesForChirps :many
+SELECT in_reply_to, COUNT(*) AS reply_count
+FROM chirps
+WHERE in_reply_to = ANY(sqlc.arg('chirp_ids')::uuid[])
+  AND deleted_at IS NULL
+GROUP BY in_reply_to;
+
+-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors AS (
+  SELECT c.id, c.created_at, c.updated_at, c.body, c.user_id, c.in_reply_to, c.deleted_at, 1 AS depth
+  FROM chirps c
+  WHERE c.id = (SELECT p.in_reply_to FROM chirps p WHERE p.id = sqlc.arg('chirp_id')::uuid)
+  UNION ALL
+  SELECT c.id, c.created_at, c.updated_at, c.body, c.user_id, c.in_reply_to, c.deleted_at, a.depth + 1
+  FROM chirps c
+  JOIN ancestors a ON c.id = a.in_reply_to
+)
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at
+FROM ancestors
+ORDER BY depth DESC;
+
+-- name: GetChirpDescendants :many
+WITH RECURSIVE descendants AS (
+  SELECT c.id, c.created_at, c.updated_at, c.body, c.user_id, c.in_reply_to, c.deleted_at
+  FROM chirps c
+  WHERE c.in_reply_to = sqlc.arg('chirp_id')::uuid
+  UNION ALL
+  SELECT c.id, c.created_at, c.updated_at, c.body, c.user_id, c.in_reply_to, c.deleted_at
+  FROM chirps c
+  JOIN descendants d ON c.in_reply_to = d.id
+)
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at
+FROM descendants
+WHERE (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (created_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('limit');
+
+-- name: SearchChirps :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at,
+       ts_rank(search_vector, websearch_to_tsquery('english', sqlc.arg('query')::text)) AS rank
+FROM chirps
+WHERE search_vector @@ websearch_to_tsquery('english', sqlc.arg('query')::text)
+  AND deleted_at IS NULL
+  AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+  AND (sqlc.narg('since')::timestamp IS NULL OR created_at >= sqlc.narg('since')::timestamp)
+  AND (sqlc.narg('until')::timestamp IS NULL OR created_at < sqlc.narg('until')::timestamp)
+  AND (sqlc.narg('cursor_rank')::real IS NULL
+       OR (ts_rank(search_vector, websearch_to_tsquery('english', sqlc.arg('query')::text)), created_at, id)
+          < (sqlc.narg('cursor_rank')::real, sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY rank DESC, created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
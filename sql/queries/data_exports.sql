@@ -0,0 +1,43 @@
+-- name: CreateDataExport :one
+INSERT INTO data_exports (id, user_id, created_at, updated_at, status)
+VALUES (gen_random_uuid(), $1, NOW(), NOW(), 'pending')
+
+RETURNING *;
+
+-- name: GetDataExport :one
+SELECT id, user_id, created_at, updated_at, status, storage_key, expires_at
+FROM data_exports
+WHERE id = $1;
+
+-- name: GetLatestDataExport :one
+SELECT id, user_id, created_at, updated_at, status, storage_key, expires_at
+FROM data_exports
+WHERE user_id = $1
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: CompleteDataExport :exec
+UPDATE data_exports
+SET status = 'ready',
+    storage_key = $2,
+    expires_at = $3,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: FailDataExport :exec
+UPDATE data_exports
+SET status = 'failed',
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: ListUserDataExportKeys :many
+SELECT storage_key
+FROM data_exports
+WHERE user_id = $1 AND storage_key IS NOT NULL;
+
+-- name: DeleteStaleDataExports :many
+DELETE FROM data_exports
+WHERE expires_at <= NOW()
+   OR (status <> 'ready' AND created_at < $1)
+
+RETURNING storage_key;
@@ -0,0 +1,16 @@
+-- name: CreateEmailVerificationToken :exec
+INSERT INTO email_verification_tokens (token_hash, user_id, email, created_at, expires_at)
+VALUES ($1, $2, $3, NOW(), $4);
+
+-- name: UseEmailVerificationToken :one
+UPDATE email_verification_tokens
+SET used_at = NOW()
+WHERE token_hash = $1
+AND used_at IS NULL
+AND expires_at > NOW()
+RETURNING user_id, email;
+
+-- name: DeleteStaleEmailVerificationTokens :execrows
+DELETE FROM email_verification_tokens
+WHERE expires_at < NOW()
+OR used_at IS NOT NULL;
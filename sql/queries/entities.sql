@@ -0,0 +1,36 @@
+-- name: AddChirpHashtags :exec
+INSERT INTO chirp_hashtags (chirp_id, tag, created_at)
+SELECT sqlc.arg('chirp_id')::uuid, unnest(sqlc.arg('tags')::text[]), sqlc.arg('created_at')::timestamp
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteChirpHashtags :exec
+DELETE FROM chirp_hashtags
+WHERE chirp_id = $1;
+
+-- name: AddChirpMentions :exec
+INSERT INTO chirp_mentions (chirp_id, handle, created_at)
+SELECT sqlc.arg('chirp_id')::uuid, unnest(sqlc.arg('handles')::text[]), sqlc.arg('created_at')::timestamp
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteChirpMentions :exec
+DELETE FROM chirp_mentions
+WHERE chirp_id = $1;
+
+-- name: GetChirpsByHashtag :many
+SELECT c.id, c.created_at, c.updated_at, c.body, c.user_id, c.in_reply_to, c.deleted_at
+FROM chirp_hashtags h
+JOIN chirps c ON c.id = h.chirp_id
+WHERE h.tag = sqlc.arg('tag')
+  AND c.deleted_at IS NULL
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (c.created_at, c.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY c.created_at DESC, c.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: GetTrendingHashtags :many
+SELECT tag, COUNT(*) AS chirp_count
+FROM chirp_hashtags
+WHERE created_at >= sqlc.arg('since')::timestamp
+GROUP BY tag
+ORDER BY chirp_count DESC, tag ASC
+LIMIT sqlc.arg('limit');
@@ -0,0 +1,37 @@
+-- name: LikeChirp :exec
+INSERT INTO chirp_likes (chirp_id, user_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: UnlikeChirp :exec
+DELETE FROM chirp_likes
+WHERE chirp_id = $1 AND user_id = $2;
+
+-- name: CountLikesForChirps :many
+SELECT chirp_id, COUNT(*) AS like_count
+FROM chirp_likes
+WHERE chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+GROUP BY chirp_id;
+
+-- name: GetLikedChirpIds :many
+SELECT chirp_id
+FROM chirp_likes
+WHERE user_id = sqlc.arg('user_id')
+  AND chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[]);
+
+-- name: GetChirpsLikedByUser :many
+SELECT c.id, c.created_at, c.updated_at, c.body, c.user_id, c.in_reply_to, c.deleted_at, l.created_at AS liked_at
+FROM chirp_likes l
+JOIN chirps c ON c.id = l.chirp_id
+WHERE l.user_id = sqlc.arg('user_id')
+  AND c.deleted_at IS NULL
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (l.created_at, c.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY l.created_at DESC, c.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: ListUserLikes :many
+SELECT chirp_id, created_at
+FROM chirp_likes
+WHERE user_id = $1
+ORDER BY created_at;
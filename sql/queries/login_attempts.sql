@@ -0,0 +1,30 @@
+-- name: GetLoginBlockedUntil :one
+SELECT MAX(blocked_until)::timestamp AS blocked_until
+FROM login_attempts
+WHERE key = ANY(sqlc.arg('keys')::text[])
+AND blocked_until > NOW();
+
+-- name: RecordLoginFailure :one
+INSERT INTO login_attempts (key, failures, last_failed_at)
+VALUES (sqlc.arg('key'), 1, NOW())
+ON CONFLICT (key) DO UPDATE
+SET failures = CASE
+        WHEN login_attempts.last_failed_at < sqlc.arg('reset_before') THEN 1
+        ELSE login_attempts.failures + 1
+    END,
+    last_failed_at = NOW()
+RETURNING failures;
+
+-- name: SetLoginBlockedUntil :exec
+UPDATE login_attempts
+SET blocked_until = $2
+WHERE key = $1;
+
+-- name: ClearLoginFailures :execrows
+DELETE FROM login_attempts
+WHERE key = $1;
+
+-- name: DeleteStaleLoginAttempts :execrows
+DELETE FROM login_attempts
+WHERE last_failed_at < $1
+AND (blocked_until IS NULL OR blocked_until < NOW());
@@ -0,0 +1,51 @@
+-- name: CreateMedia :one
+INSERT INTO media (id, created_at, user_id, storage_key, content_type, size_bytes)
+VALUES ($1, NOW(), $2, $3, $4, $5)
+
+RETURNING *;
+
+-- name: GetMediaByIds :many
+SELECT id, created_at, user_id, storage_key, content_type, size_bytes
+FROM media
+WHERE id = ANY(sqlc.arg('media_ids')::uuid[]);
+
+-- name: AttachChirpMedia :exec
+INSERT INTO chirp_media (chirp_id, media_id, position)
+VALUES ($1, $2, $3);
+
+-- name: GetMediaForChirps :many
+SELECT cm.chirp_id, cm.position, m.id, m.created_at, m.user_id, m.storage_key, m.content_type, m.size_bytes
+FROM chirp_media cm
+JOIN media m ON m.id = cm.media_id
+WHERE cm.chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+ORDER BY cm.chirp_id, cm.position;
+
+-- name: ListUserMediaKeys :many
+SELECT storage_key
+FROM media
+WHERE user_id = $1;
+
+-- name: DeleteChirpMedia :many
+DELETE FROM media m
+WHERE m.id IN (SELECT cm.media_id FROM chirp_media cm WHERE cm.chirp_id = sqlc.arg('chirp_id'))
+  AND NOT EXISTS (
+    SELECT 1 FROM chirp_media o
+    WHERE o.media_id = m.id AND o.chirp_id <> sqlc.arg('chirp_id')
+  )
+
+RETURNING m.storage_key;
+
+-- name: ListAllMediaKeys :many
+SELECT storage_key
+FROM media;
+
+-- name: DeleteUnattachedMedia :many
+DELETE FROM media m
+WHERE m.id IN (
+    SELECT u.id FROM media u
+    WHERE u.created_at < sqlc.arg('created_before')
+      AND NOT EXISTS (SELECT 1 FROM chirp_media cm WHERE cm.media_id = u.id)
+    LIMIT sqlc.arg('limit')
+  )
+
+RETURNING m.storage_key;
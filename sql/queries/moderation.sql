@@ -0,0 +1,43 @@
+-- name: ListBannedWords :many
+SELECT id, created_at, updated_at, word, action
+FROM banned_words
+ORDER BY word ASC;
+
+-- name: GetBannedWord :one
+SELECT id, created_at, updated_at, word, action
+FROM banned_words
+WHERE id = $1;
+
+-- name: CreateBannedWord :one
+INSERT INTO banned_words (id, created_at, updated_at, word, action)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2)
+
+RETURNING *;
+
+-- name: UpdateBannedWord :one
+UPDATE banned_words
+SET word = $2,
+    action = $3,
+    updated_at = NOW()
+WHERE id = $1
+
+RETURNING *;
+
+-- name: DeleteBannedWord :execrows
+DELETE FROM banned_words
+WHERE id = $1;
+
+-- name: FlagChirp :exec
+INSERT INTO chirp_flags (id, chirp_id, words, created_at, reviewed_at)
+VALUES (gen_random_uuid(), $1, $2, NOW(), NULL);
+
+-- name: ListPendingChirpFlags :many
+SELECT id, chirp_id, words, created_at, reviewed_at
+FROM chirp_flags
+WHERE reviewed_at IS NULL
+ORDER BY created_at ASC;
+
+-- name: ResolveChirpFlag :execrows
+UPDATE chirp_flags
+SET reviewed_at = NOW()
+WHERE id = $1 AND reviewed_at IS NULL;
@@ -0,0 +1,23 @@
+-- name: GetUserRoleForUpdate :one
+SELECT role
+FROM users
+WHERE id = $1
+FOR UPDATE;
+
+-- name: SetUserRole :exec
+UPDATE users
+SET role = $2,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: CreateRoleChange :one
+INSERT INTO role_changes (id, created_at, user_id, changed_by, old_role, new_role)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3, $4)
+
+RETURNING *;
+
+-- name: ListRoleChanges :many
+SELECT id, created_at, user_id, changed_by, old_role, new_role
+FROM role_changes
+WHERE sqlc.narg('user_id')::uuid IS NULL OR user_id = sqlc.narg('user_id')::uuid
+ORDER BY created_at DESC, id DESC;
@@ -0,0 +1,2 @@
+-- name: NotifyChirpEvent :exec
+SELECT pg_notify('chirp_events', sqlc.arg('payload')::text);
@@ -0,0 +1,53 @@
+-- name: StartTOTPEnrolment :execrows
+INSERT INTO user_totp (user_id, secret, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (user_id) DO UPDATE
+SET secret = EXCLUDED.secret,
+    created_at = NOW()
+WHERE user_totp.confirmed_at IS NULL;
+
+-- name: GetUserTOTP :one
+SELECT user_id, secret, created_at, confirmed_at, last_used_step
+FROM user_totp
+WHERE user_id = $1;
+
+-- name: ConfirmTOTP :execrows
+UPDATE user_totp
+SET confirmed_at = NOW(),
+    last_used_step = $2
+WHERE user_id = $1
+AND confirmed_at IS NULL;
+
+-- name: UseTOTPStep :execrows
+UPDATE user_totp
+SET last_used_step = $2
+WHERE user_id = $1
+AND last_used_step < $2;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE user_id = $1;
+
+-- name: AddRecoveryCodes :exec
+INSERT INTO recovery_codes (id, user_id, code_hash, created_at)
+SELECT gen_random_uuid(), sqlc.arg('user_id'), unnest(sqlc.arg('code_hashes')::text[]), NOW();
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes
+SET used_at = NOW()
+WHERE user_id = $1
+AND code_hash = $2
+AND used_at IS NULL;
+
+-- name: UseMFAToken :execrows
+INSERT INTO used_mfa_tokens (token_id, expires_at)
+VALUES ($1, $2)
+ON CONFLICT (token_id) DO NOTHING;
+
+-- name: ReleaseMFAToken :exec
+DELETE FROM used_mfa_tokens
+WHERE token_id = $1;
+
+-- name: DeleteExpiredMFATokens :execrows
+DELETE FROM used_mfa_tokens
+WHERE expires_at < NOW();
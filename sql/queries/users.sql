@@ -6,17 +6,10 @@ RETURNING id, created_at, updated_at, email;
 
 
 -- name: GetUserByEmail :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, token_version, role, email_verified_at, pending_email, delete_after
 FROM users
 WHERE email = $1;
 
--- name: UpdateUserEmailAndPassword :exec
-UPDATE users
-SET email = $2,
-    hashed_password = $3,
-    updated_at = NOW()
-WHERE id = $1;
-
 -- name: UpgradeUser :exec
 UPDATE users
 SET is_chirpy_red = TRUE,
@@ -26,3 +19,69 @@ WHERE id = $1;
 -- name: DeleteAllUsers :exec
 DElETE
 FROM users;
+
+-- name: GetUserById :one
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, token_version, role, email_verified_at, pending_email, delete_after
+FROM users
+WHERE id = $1;
+
+-- name: GetUserTokenVersion :one
+SELECT token_version
+FROM users
+WHERE id = $1;
+
+-- name: GetUserAuthState :one
+SELECT token_version, is_chirpy_red, role, email_verified_at IS NOT NULL AS email_verified
+FROM users
+WHERE id = $1;
+
+-- name: BumpUserTokenVersion :exec
+UPDATE users
+SET token_version = token_version + 1,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET hashed_password = $2,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: SetUserPendingEmail :exec
+UPDATE users
+SET pending_email = $2,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: VerifyUserEmail :execrows
+-- Confirming the current address keeps a pending change to another one.
+UPDATE users
+SET email = $2,
+    pending_email = CASE WHEN pending_email = $2 THEN NULL ELSE pending_email END,
+    email_verified_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+AND (email = $2 OR pending_email = $2);
+
+-- name: ScheduleUserDeletion :exec
+UPDATE users
+SET delete_after = $2,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: CancelUserDeletion :execrows
+UPDATE users
+SET delete_after = NULL,
+    updated_at = NOW()
+WHERE id = $1 AND delete_after IS NOT NULL;
+
+-- name: ListUsersDueForDeletion :many
+SELECT id
+FROM users
+WHERE delete_after <= NOW()
+ORDER BY delete_after
+LIMIT $1;
+
+-- name: DeleteUser :execrows
+DELETE FROM users
+WHERE id = $1 AND delete_after <= NOW();
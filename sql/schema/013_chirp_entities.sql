@@ -0,0 +1,25 @@
+-- +goose Up
+CREATE TABLE chirp_hashtags(
+  chirp_id UUID NOT NULL,
+  tag TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  PRIMARY KEY(chirp_id, tag),
+  FOREIGN KEY(chirp_id) REFERENCES chirps(id) ON DELETE CASCADE
+);
+
+CREATE INDEX chirp_hashtags_tag_created_at_idx ON chirp_hashtags (tag, created_at);
+CREATE INDEX chirp_hashtags_created_at_idx ON chirp_hashtags (created_at);
+
+CREATE TABLE chirp_mentions(
+  chirp_id UUID NOT NULL,
+  handle TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  PRIMARY KEY(chirp_id, handle),
+  FOREIGN KEY(chirp_id) REFERENCES chirps(id) ON DELETE CASCADE
+);
+
+CREATE INDEX chirp_mentions_handle_idx ON chirp_mentions (handle);
+
+-- +goose Down
+DROP TABLE chirp_mentions;
+DROP TABLE chirp_hashtags;
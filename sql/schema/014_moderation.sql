@@ -0,0 +1,28 @@
+-- +goose Up
+CREATE TABLE banned_words(
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL,
+  word TEXT UNIQUE NOT NULL,
+  action TEXT NOT NULL CHECK (action IN ('mask', 'reject', 'flag'))
+);
+
+INSERT INTO banned_words (id, created_at, updated_at, word, action)
+VALUES (gen_random_uuid(), NOW(), NOW(), 'kerfuffle', 'mask'),
+       (gen_random_uuid(), NOW(), NOW(), 'sharbert', 'mask'),
+       (gen_random_uuid(), NOW(), NOW(), 'fornax', 'mask');
+
+CREATE TABLE chirp_flags(
+  id UUID PRIMARY KEY,
+  chirp_id UUID NOT NULL,
+  words TEXT[] NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  reviewed_at TIMESTAMP,
+  FOREIGN KEY(chirp_id) REFERENCES chirps(id) ON DELETE CASCADE
+);
+
+CREATE INDEX chirp_flags_pending_idx ON chirp_flags (created_at) WHERE reviewed_at IS NULL;
+
+-- +goose Down
+DROP TABLE chirp_flags;
+DROP TABLE banned_words;
@@ -0,0 +1,24 @@
+-- +goose Up
+CREATE TABLE media(
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  user_id UUID NOT NULL,
+  storage_key TEXT UNIQUE NOT NULL,
+  content_type TEXT NOT NULL,
+  size_bytes BIGINT NOT NULL,
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE chirp_media(
+  chirp_id UUID NOT NULL,
+  media_id UUID NOT NULL,
+  position SMALLINT NOT NULL,
+  PRIMARY KEY(chirp_id, media_id),
+  UNIQUE(chirp_id, position),
+  FOREIGN KEY(chirp_id) REFERENCES chirps(id) ON DELETE CASCADE,
+  FOREIGN KEY(media_id) REFERENCES media(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE chirp_media;
+DROP TABLE media;
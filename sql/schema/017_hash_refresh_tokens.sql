@@ -0,0 +1,22 @@
+-- +goose Up
+-- Only the SHA-256 digest of a refresh token is kept. Existing rows are
+-- converted in place so clients holding a token stay logged in.
+ALTER TABLE refresh_tokens
+RENAME COLUMN token TO token_hash;
+
+ALTER TABLE refresh_tokens
+ALTER COLUMN token_hash TYPE TEXT
+USING encode(sha256(convert_to(rtrim(token_hash), 'UTF8')), 'hex');
+
+-- +goose Down
+-- Digests can't be turned back into tokens, so every session is revoked.
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE revoked_at IS NULL;
+
+ALTER TABLE refresh_tokens
+ALTER COLUMN token_hash TYPE CHAR(256);
+
+ALTER TABLE refresh_tokens
+RENAME COLUMN token_hash TO token;
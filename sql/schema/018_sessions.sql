@@ -0,0 +1,35 @@
+-- +goose Up
+-- A session is a refresh token family. signed_in_at is carried over on
+-- every rotation so it keeps the time of the original login.
+ALTER TABLE refresh_tokens
+ADD COLUMN user_agent TEXT NOT NULL DEFAULT '',
+ADD COLUMN ip_address TEXT NOT NULL DEFAULT '',
+ADD COLUMN signed_in_at TIMESTAMP,
+ADD COLUMN last_used_at TIMESTAMP;
+
+UPDATE refresh_tokens
+SET signed_in_at = created_at,
+    last_used_at = updated_at;
+
+ALTER TABLE refresh_tokens
+ALTER COLUMN signed_in_at SET NOT NULL,
+ALTER COLUMN last_used_at SET NOT NULL;
+
+CREATE INDEX refresh_tokens_active_user_id_idx ON refresh_tokens (user_id) WHERE revoked_at IS NULL;
+
+-- Access JWTs carry the version they were issued under; bumping it
+-- invalidates all of them at once.
+ALTER TABLE users
+ADD COLUMN token_version INTEGER NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN token_version;
+
+DROP INDEX refresh_tokens_active_user_id_idx;
+
+ALTER TABLE refresh_tokens
+DROP COLUMN last_used_at,
+DROP COLUMN signed_in_at,
+DROP COLUMN ip_address,
+DROP COLUMN user_agent;
@@ -0,0 +1,24 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN role TEXT NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'moderator', 'admin'));
+
+-- changed_by is NULL for changes made outside the API, such as the
+-- ADMIN_EMAIL bootstrap at startup.
+CREATE TABLE role_changes(
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  user_id UUID NOT NULL,
+  changed_by UUID,
+  old_role TEXT NOT NULL,
+  new_role TEXT NOT NULL,
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+  FOREIGN KEY(changed_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX role_changes_user_id_created_at_idx ON role_changes (user_id, created_at);
+
+-- +goose Down
+DROP TABLE role_changes;
+
+ALTER TABLE users
+DROP COLUMN role;
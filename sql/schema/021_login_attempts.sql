@@ -0,0 +1,12 @@
+-- +goose Up
+-- Failed logins are counted per key, which is either "email:<address>" or
+-- "ip:<address>", whether or not the email belongs to an account.
+CREATE TABLE login_attempts(
+  key TEXT PRIMARY KEY,
+  failures INTEGER NOT NULL,
+  last_failed_at TIMESTAMP NOT NULL,
+  blocked_until TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE login_attempts;
@@ -0,0 +1,25 @@
+-- +goose Up
+-- A secret without confirmed_at is still being enrolled and isn't asked
+-- for at login.
+CREATE TABLE user_totp(
+  user_id UUID PRIMARY KEY,
+  secret TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  confirmed_at TIMESTAMP,
+  last_used_step BIGINT NOT NULL DEFAULT 0,
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE recovery_codes(
+  id UUID PRIMARY KEY,
+  user_id UUID NOT NULL,
+  code_hash TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  used_at TIMESTAMP,
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+  UNIQUE(user_id, code_hash)
+);
+
+-- +goose Down
+DROP TABLE recovery_codes;
+DROP TABLE user_totp;
@@ -0,0 +1,30 @@
+-- +goose Up
+-- A changed email is kept in pending_email until the new address is
+-- confirmed; logins and mail keep using the old one until then.
+ALTER TABLE users
+ADD COLUMN email_verified_at TIMESTAMP,
+ADD COLUMN pending_email TEXT;
+
+-- Accounts made before verification existed are taken as verified, so
+-- REQUIRE_VERIFIED_EMAIL doesn't lock them out.
+UPDATE users
+SET email_verified_at = NOW();
+
+CREATE TABLE email_verification_tokens(
+  token_hash TEXT PRIMARY KEY,
+  user_id UUID NOT NULL,
+  email TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  expires_at TIMESTAMP NOT NULL,
+  used_at TIMESTAMP,
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX email_verification_tokens_user_id_idx ON email_verification_tokens (user_id);
+
+-- +goose Down
+DROP TABLE email_verification_tokens;
+
+ALTER TABLE users
+DROP COLUMN pending_email,
+DROP COLUMN email_verified_at;
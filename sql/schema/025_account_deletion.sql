@@ -0,0 +1,27 @@
+-- +goose Up
+-- A deleted account is kept until delete_after so that signing in again
+-- can undo it. After that the row goes, taking everything that references
+-- it with it.
+ALTER TABLE users
+ADD COLUMN delete_after TIMESTAMP;
+
+CREATE INDEX users_delete_after_idx ON users (delete_after) WHERE delete_after IS NOT NULL;
+
+CREATE TABLE data_exports(
+  id UUID PRIMARY KEY,
+  user_id UUID NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL,
+  status TEXT NOT NULL,
+  storage_key TEXT,
+  expires_at TIMESTAMP,
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX data_exports_user_id_idx ON data_exports (user_id, created_at);
+
+-- +goose Down
+DROP TABLE data_exports;
+
+ALTER TABLE users
+DROP COLUMN delete_after;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- Chirps posted before 013 never had their tags and mentions indexed. The
+-- patterns follow entities.Parse: a '#' or '@' that doesn't follow a word
+-- character, then one or more letters, digits or underscores.
+INSERT INTO chirp_hashtags (chirp_id, tag, created_at)
+SELECT c.id, lower(m[1]), c.created_at
+FROM chirps c,
+     regexp_matches(c.body, '(?:^|[^[:alnum:]_])#([[:alnum:]_]+)', 'g') AS m
+WHERE c.deleted_at IS NULL
+ON CONFLICT DO NOTHING;
+
+INSERT INTO chirp_mentions (chirp_id, handle, created_at)
+SELECT c.id, lower(m[1]), c.created_at
+FROM chirps c,
+     regexp_matches(c.body, '(?:^|[^[:alnum:]_])@([[:alnum:]_]+)', 'g') AS m
+WHERE c.deleted_at IS NULL
+ON CONFLICT DO NOTHING;
+
+-- +goose Down
+-- Backfilled rows can't be told apart from the others, so they stay.
@@ -0,0 +1,10 @@
+-- +goose Up
+-- MFA tokens are JWTs; this remembers the ones already exchanged for a
+-- session until they would have expired anyway.
+CREATE TABLE used_mfa_tokens(
+  token_id TEXT PRIMARY KEY,
+  expires_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE used_mfa_tokens;